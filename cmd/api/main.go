@@ -1,19 +1,87 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
+	"example.com/notes-api/internal/backup"
+	"example.com/notes-api/internal/config"
+	"example.com/notes-api/internal/core"
 	httpx "example.com/notes-api/internal/http"
 	"example.com/notes-api/internal/http/handlers"
+	"example.com/notes-api/internal/idcodec"
 	"example.com/notes-api/internal/repo"
 )
 
 func main() {
-	repo := repo.NewNoteRepoMem()
-	h := &handlers.Handler{Repo: repo}
-	r := httpx.NewRouter(h)
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	notes := repo.NewNoteRepoMem()
+	h := &handlers.Handler{
+		Repo:             notes,
+		Snapshots:        repo.NewSnapshotRepoMem(),
+		Views:            repo.NewViewRepoMem(),
+		ImportConflict:   cfg.ImportConflict,
+		AllowEmptyPatch:  cfg.AllowEmptyPatch,
+		DeleteDependents: cfg.DeleteDependents,
+		SoftLimits:       make(map[string]bool),
+		RetryAfter:       cfg.SoftLimitRetryAfter,
+	}
+	for _, limit := range cfg.SoftLimits {
+		h.SoftLimits[limit] = true
+	}
+	if cfg.TrimTrailingWhitespace {
+		h.Transformers = append(h.Transformers, core.TrimTrailingWhitespace)
+	}
+	if cfg.CollapseBlankLines {
+		h.Transformers = append(h.Transformers, core.CollapseBlankLines)
+	}
+	if cfg.IDSalt != "" {
+		h.IDCodec = idcodec.New(cfg.IDSalt)
+	}
+	r := httpx.NewRouter(h, cfg)
+
+	var wg sync.WaitGroup
+	if cfg.BackupDir != "" {
+		b := &backup.Backuper{
+			Repo:     notes,
+			Dir:      cfg.BackupDir,
+			Interval: cfg.BackupInterval,
+			Keep:     cfg.BackupKeep,
+		}
+		h.Backups = b
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Run(ctx)
+		}()
+	}
+
+	srv := &http.Server{Addr: ":8080", Handler: r}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
 
 	log.Println("Server started at :8080")
-	log.Fatal(http.ListenAndServe(":8080", r))
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+
+	wg.Wait()
+	log.Println("Server stopped")
 }
@@ -2,4 +2,4 @@ module example.com/notes-api
 
 go 1.24.4
 
-require github.com/go-chi/chi/v5 v5.2.3 // indirect
+require github.com/go-chi/chi/v5 v5.2.3
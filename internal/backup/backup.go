@@ -0,0 +1,213 @@
+// Package backup периодически сохраняет полный набор заметок в JSON-файлы на диск.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"example.com/notes-api/internal/core"
+	"example.com/notes-api/internal/repo"
+)
+
+var (
+	ErrBackupNotFound = errors.New("backup not found")
+	ErrBackupCorrupt  = errors.New("backup is corrupt")
+)
+
+const (
+	filePrefix = "notes-"
+	fileSuffix = ".json"
+
+	// timeLayout дает имена файлов, которые сортируются по времени как строки
+	timeLayout = "20060102T150405.000Z"
+)
+
+// Backuper пишет снимки хранилища в Dir и хранит только Keep последних
+type Backuper struct {
+	Repo     *repo.NoteRepoMem
+	Dir      string
+	Interval time.Duration
+	Keep     int
+}
+
+// Run делает резервную копию раз в Interval, пока не отменен ctx
+func (b *Backuper) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			name, err := b.WriteBackup()
+			if err != nil {
+				log.Printf("backup: %v", err)
+				continue
+			}
+			log.Printf("backup: wrote %s", name)
+		}
+	}
+}
+
+// WriteBackup сохраняет все заметки в новый файл и удаляет лишние старые копии.
+// Возвращает имя созданного файла.
+func (b *Backuper) WriteBackup() (string, error) {
+	notes, err := b.Repo.GetAll()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(b.Dir, 0o755); err != nil {
+		return "", err
+	}
+
+	name := filePrefix + time.Now().UTC().Format(timeLayout) + fileSuffix
+
+	// пишем во временный файл и переименовываем, чтобы не оставить обрезанную копию
+	tmp, err := os.CreateTemp(b.Dir, ".tmp-"+filePrefix+"*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(b.Dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	if err := b.prune(); err != nil {
+		return name, fmt.Errorf("prune: %w", err)
+	}
+
+	return name, nil
+}
+
+// prune удаляет все копии, кроме Keep самых новых
+func (b *Backuper) prune() error {
+	names, err := b.names()
+	if err != nil {
+		return err
+	}
+
+	if b.Keep <= 0 || len(names) <= b.Keep {
+		return nil
+	}
+
+	for _, name := range names[:len(names)-b.Keep] {
+		if err := os.Remove(filepath.Join(b.Dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// names возвращает имена файлов резервных копий от старых к новым
+func (b *Backuper) names() ([]string, error) {
+	entries, err := os.ReadDir(b.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && isBackupName(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func isBackupName(name string) bool {
+	if !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileSuffix) {
+		return false
+	}
+	_, err := time.Parse(timeLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+	return err == nil
+}
+
+// Info описывает файл резервной копии
+type Info struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Notes     int       `json:"notes"`
+	Valid     bool      `json:"valid"`
+}
+
+// List возвращает резервные копии от новых к старым. Поврежденные файлы
+// попадают в список с Valid=false, чтобы их было видно.
+func (b *Backuper) List() ([]Info, error) {
+	names, err := b.names()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []Info{}, nil
+		}
+		return nil, err
+	}
+
+	infos := make([]Info, 0, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		name := names[i]
+		created, _ := time.Parse(timeLayout, strings.TrimSuffix(strings.TrimPrefix(name, filePrefix), fileSuffix))
+		info := Info{Name: name, CreatedAt: created}
+		if notes, err := b.Load(name); err == nil {
+			info.Notes = len(notes)
+			info.Valid = true
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Load читает заметки из резервной копии по имени файла
+func (b *Backuper) Load(name string) ([]core.Note, error) {
+	// принимаем только имена, которые выдает WriteBackup, - без путей
+	if !isBackupName(name) || filepath.Base(name) != name {
+		return nil, ErrBackupNotFound
+	}
+
+	data, err := os.ReadFile(filepath.Join(b.Dir, name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrBackupNotFound
+		}
+		return nil, err
+	}
+
+	var notes []core.Note
+	if err := json.Unmarshal(data, &notes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBackupCorrupt, err)
+	}
+
+	seen := make(map[int64]bool, len(notes))
+	for _, n := range notes {
+		if n.ID <= 0 || seen[n.ID] {
+			return nil, fmt.Errorf("%w: bad or duplicate note ID %d", ErrBackupCorrupt, n.ID)
+		}
+		seen[n.ID] = true
+	}
+
+	return notes, nil
+}
@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"example.com/notes-api/internal/core"
+	"example.com/notes-api/internal/repo"
+)
+
+func dirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRunWritesAndPrunesBackups(t *testing.T) {
+	dir := t.TempDir()
+	old := filePrefix + "20000101T000000.000Z" + fileSuffix
+	for _, name := range []string{old, "readme.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("[]"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notes := repo.NewNoteRepoMem()
+	notes.Create(core.Note{Title: "a"})
+	b := &Backuper{Repo: notes, Dir: dir, Interval: 5 * time.Millisecond, Keep: 2}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.Run(ctx)
+		close(done)
+	}()
+
+	// старая копия удаляется, когда новых становится больше Keep
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, old)); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("old backup was never pruned; dir = %v", dirNames(t, dir))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	backups, err := b.names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("backups = %v, want exactly Keep=2", backups)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "readme.txt")); err != nil {
+		t.Fatalf("unrelated file was removed: %v", err)
+	}
+
+	loaded, err := b.Load(backups[1])
+	if err != nil || len(loaded) != 1 || loaded[0].Title != "a" {
+		t.Fatalf("Load(%s) = %+v, %v", backups[1], loaded, err)
+	}
+}
+
+func TestListAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	notes := repo.NewNoteRepoMem()
+	notes.Create(core.Note{Title: "a"})
+	b := &Backuper{Repo: notes, Dir: dir, Keep: 10}
+
+	first, err := b.WriteBackup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes.Create(core.Note{Title: "b"})
+	time.Sleep(2 * time.Millisecond) // имена различаются с точностью до миллисекунды
+	second, err := b.WriteBackup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corrupt := filePrefix + "20000101T000000.000Z" + fileSuffix
+	os.WriteFile(filepath.Join(dir, corrupt), []byte("{not json"), 0o644)
+
+	infos, err := b.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 3 || infos[0].Name != second || infos[1].Name != first || infos[2].Name != corrupt {
+		t.Fatalf("List() = %+v, want newest first", infos)
+	}
+	if infos[0].Notes != 2 || !infos[0].Valid || infos[1].Notes != 1 || infos[2].Valid {
+		t.Fatalf("List() = %+v, want note counts 2, 1 and the corrupt file invalid", infos)
+	}
+
+	if _, err := b.Load(corrupt); err == nil {
+		t.Fatal("corrupt backup loaded without error")
+	}
+	for _, bad := range []string{"../" + first, "notes.json", "missing"} {
+		if _, err := b.Load(bad); err != ErrBackupNotFound {
+			t.Errorf("Load(%q): err = %v, want ErrBackupNotFound", bad, err)
+		}
+	}
+}
+
+func TestListWithoutDirectory(t *testing.T) {
+	b := &Backuper{Dir: filepath.Join(t.TempDir(), "absent")}
+	infos, err := b.List()
+	if err != nil || len(infos) != 0 {
+		t.Fatalf("List() = %v, %v; want empty list", infos, err)
+	}
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RequestIDUUID   = "uuid"
+	RequestIDBase62 = "base62"
+	RequestIDULID   = "ulid"
+
+	ImportSkip      = "skip"
+	ImportOverwrite = "overwrite"
+
+	DependentsIgnore = "ignore"
+	DependentsBlock  = "block"
+	DependentsMark   = "mark"
+
+	LimitDeltaItems = "delta_items"
+	LimitOldest     = "oldest_limit"
+	LimitImportLine = "import_line"
+	LimitGraphDepth = "graph_depth"
+)
+
+// Config - настройки сервиса, читаются из переменных окружения
+type Config struct {
+	RequestIDFormat string
+	RequestIDMaxLen int
+
+	// TrimTrailingWhitespace включает удаление пробелов в конце строк содержимого при записи
+	TrimTrailingWhitespace bool
+
+	// CollapseBlankLines включает обрезку краев содержимого и схлопывание пустых строк при записи
+	CollapseBlankLines bool
+
+	// LogExclude - шаблоны путей (path.Match), запросы к которым не пишутся в лог
+	LogExclude []string
+
+	// AdminAPIKey защищает /admin; пустой ключ отключает админские маршруты
+	AdminAPIKey string `secret:"true"`
+
+	// IDSalt включает кодирование ID заметок в непрозрачные строки; пустая соль - числовые ID
+	IDSalt string `secret:"true"`
+
+	// ImportConflict - что делать при импорте с сохранением ID, если ID занят: skip или overwrite
+	ImportConflict string
+
+	// AllowEmptyPatch разрешает PATCH без полей как no-op (200) вместо 400
+	AllowEmptyPatch bool
+
+	// DeleteDependents - что делать при удалении заметки, которую подключают другие: ignore, block или mark
+	DeleteDependents string
+
+	// BackupDir включает периодические резервные копии в этот каталог; пусто - копии не делаются
+	BackupDir      string
+	BackupInterval time.Duration
+	BackupKeep     int
+
+	// SoftLimits - лимиты, превышение которых отвечает 429 с Retry-After вместо 400
+	SoftLimits          []string
+	SoftLimitRetryAfter time.Duration
+}
+
+// Load читает конфигурацию из окружения и проверяет значения
+func Load() (Config, error) {
+	cfg := Config{
+		RequestIDFormat:  getEnv("NOTES_REQUEST_ID_FORMAT", RequestIDUUID),
+		LogExclude:       getEnvList("NOTES_LOG_EXCLUDE", []string{"/health", "/metrics"}),
+		AdminAPIKey:      os.Getenv("NOTES_ADMIN_API_KEY"),
+		IDSalt:           os.Getenv("NOTES_ID_SALT"),
+		ImportConflict:   getEnv("NOTES_IMPORT_CONFLICT", ImportSkip),
+		DeleteDependents: getEnv("NOTES_DELETE_DEPENDENTS", DependentsIgnore),
+		BackupDir:        os.Getenv("NOTES_BACKUP_DIR"),
+		SoftLimits:       getEnvList("NOTES_SOFT_LIMITS", nil),
+	}
+
+	var err error
+	if cfg.RequestIDMaxLen, err = getEnvInt("NOTES_REQUEST_ID_MAX_LEN", 64); err != nil {
+		return Config{}, err
+	}
+	if cfg.TrimTrailingWhitespace, err = getEnvBool("NOTES_TRIM_TRAILING_WHITESPACE", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.CollapseBlankLines, err = getEnvBool("NOTES_COLLAPSE_BLANK_LINES", false); err != nil {
+		return Config{}, err
+	}
+	if cfg.AllowEmptyPatch, err = getEnvBool("NOTES_ALLOW_EMPTY_PATCH", false); err != nil {
+		return Config{}, err
+	}
+
+	for _, pattern := range cfg.LogExclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return Config{}, fmt.Errorf("NOTES_LOG_EXCLUDE: bad pattern %q: %w", pattern, err)
+		}
+	}
+
+	if cfg.BackupInterval, err = getEnvDuration("NOTES_BACKUP_INTERVAL", time.Hour); err != nil {
+		return Config{}, err
+	}
+	if cfg.BackupKeep, err = getEnvInt("NOTES_BACKUP_KEEP", 10); err != nil {
+		return Config{}, err
+	}
+	if cfg.BackupInterval <= 0 {
+		return Config{}, fmt.Errorf("NOTES_BACKUP_INTERVAL: must be positive")
+	}
+	if cfg.BackupKeep <= 0 {
+		return Config{}, fmt.Errorf("NOTES_BACKUP_KEEP: must be positive")
+	}
+
+	if cfg.SoftLimitRetryAfter, err = getEnvDuration("NOTES_SOFT_LIMIT_RETRY_AFTER", 30*time.Second); err != nil {
+		return Config{}, err
+	}
+	if cfg.SoftLimitRetryAfter < time.Second {
+		return Config{}, fmt.Errorf("NOTES_SOFT_LIMIT_RETRY_AFTER: must be at least 1s")
+	}
+	for _, limit := range cfg.SoftLimits {
+		switch limit {
+		case LimitDeltaItems, LimitOldest, LimitImportLine, LimitGraphDepth:
+		default:
+			return Config{}, fmt.Errorf("NOTES_SOFT_LIMITS: unknown limit %q", limit)
+		}
+	}
+
+	if cfg.ImportConflict != ImportSkip && cfg.ImportConflict != ImportOverwrite {
+		return Config{}, fmt.Errorf("NOTES_IMPORT_CONFLICT: unknown policy %q", cfg.ImportConflict)
+	}
+
+	switch cfg.DeleteDependents {
+	case DependentsIgnore, DependentsBlock, DependentsMark:
+	default:
+		return Config{}, fmt.Errorf("NOTES_DELETE_DEPENDENTS: unknown policy %q", cfg.DeleteDependents)
+	}
+
+	switch cfg.RequestIDFormat {
+	case RequestIDUUID, RequestIDBase62, RequestIDULID:
+	default:
+		return Config{}, fmt.Errorf("NOTES_REQUEST_ID_FORMAT: unknown format %q", cfg.RequestIDFormat)
+	}
+	if cfg.RequestIDMaxLen <= 0 {
+		return Config{}, fmt.Errorf("NOTES_REQUEST_ID_MAX_LEN: must be positive")
+	}
+
+	return cfg, nil
+}
+
+// Redacted возвращает конфигурацию для показа: поля с тегом secret заменяются
+// на "[redacted]" (или пустую строку, если не заданы), длительности - строками вида "1h0m0s"
+func (c Config) Redacted() map[string]interface{} {
+	out := make(map[string]interface{})
+	v := reflect.ValueOf(c)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		switch {
+		case field.Tag.Get("secret") == "true":
+			if value.IsZero() {
+				out[field.Name] = ""
+			} else {
+				out[field.Name] = "[redacted]"
+			}
+		case field.Type == reflect.TypeOf(time.Duration(0)):
+			out[field.Name] = value.Interface().(time.Duration).String()
+		default:
+			out[field.Name] = value.Interface()
+		}
+	}
+	return out
+}
+
+func getEnv(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// getEnvList читает список через запятую; пустые элементы отбрасываются
+func getEnvList(key string, def []string) []string {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return def
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvInt(key string, def int) (int, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return n, nil
+}
+
+func getEnvDuration(key string, def time.Duration) (time.Duration, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return d, nil
+}
+
+func getEnvBool(key string, def bool) (bool, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", key, err)
+	}
+	return b, nil
+}
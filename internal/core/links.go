@@ -0,0 +1,56 @@
+package core
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+// ExtractURLs возвращает внешние http(s)-ссылки из текста без повторов, в порядке появления.
+// Знаки препинания и непарные скобки в конце ссылки отбрасываются.
+func ExtractURLs(text string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, raw := range urlPattern.FindAllString(text, -1) {
+		raw = trimURLTail(raw)
+
+		u, err := url.Parse(raw)
+		if err != nil || u.Hostname() == "" || seen[raw] {
+			continue
+		}
+		seen[raw] = true
+		urls = append(urls, raw)
+	}
+	return urls
+}
+
+// trimURLTail срезает хвост, который почти наверняка относится к тексту, а не к ссылке:
+// точку в конце предложения или закрывающую скобку Markdown-ссылки
+func trimURLTail(raw string) string {
+	for {
+		trimmed := strings.TrimRight(raw, ".,;:!?")
+		for _, pair := range []string{"()", "[]"} {
+			if strings.HasSuffix(trimmed, pair[1:]) &&
+				strings.Count(trimmed, pair[:1]) < strings.Count(trimmed, pair[1:]) {
+				trimmed = trimmed[:len(trimmed)-1]
+			}
+		}
+		if trimmed == raw {
+			return raw
+		}
+		raw = trimmed
+	}
+}
+
+// URLHasDomain проверяет, что ссылка ведет на домен или его поддомен
+func URLHasDomain(raw, domain string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractURLs(t *testing.T) {
+	text := "Docs at https://go.dev/doc. See [pkg](https://pkg.go.dev/net/http) and https://go.dev/doc again."
+	want := []string{"https://go.dev/doc", "https://pkg.go.dev/net/http"}
+	if got := ExtractURLs(text); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ExtractURLs = %q, want %q", got, want)
+	}
+}
+
+func TestURLHasDomain(t *testing.T) {
+	tests := []struct {
+		url, domain string
+		want        bool
+	}{
+		{"https://go.dev/doc", "go.dev", true},
+		{"https://pkg.go.dev/x", "go.dev", true},
+		{"https://notgo.dev/", "go.dev", false},
+		{"https://GO.dev/", ".go.dev", true},
+	}
+	for _, tt := range tests {
+		if got := URLHasDomain(tt.url, tt.domain); got != tt.want {
+			t.Errorf("URLHasDomain(%q, %q) = %v, want %v", tt.url, tt.domain, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Heading - ATX-заголовок Markdown (# ... ######)
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	Line  int    `json:"line"`
+}
+
+// LintIssue - проблема разметки в строке содержимого
+type LintIssue struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+var (
+	atxHeading   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	fenceOpening = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+
+	codeSpan     = regexp.MustCompile("`+[^`]*`+")
+	unclosedLink = regexp.MustCompile(`\[[^\]]*\]\([^)]*$`)
+	emptyLink    = regexp.MustCompile(`\[[^\]]*\]\(\s*\)`)
+	emptyHeading = regexp.MustCompile(`^ {0,3}#{1,6}[ \t]*#*[ \t]*$`)
+)
+
+// Headings извлекает ATX-заголовки, пропуская блоки кода в ограждениях
+func Headings(content string) []Heading {
+	var headings []Heading
+	fence := ""
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+
+		if m := fenceOpening.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+			case m[1][0] == fence[0] && len(m[1]) >= len(fence):
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+
+		if m := atxHeading.FindStringSubmatch(line); m != nil {
+			headings = append(headings, Heading{Level: len(m[1]), Text: strings.TrimSpace(m[2]), Line: i + 1})
+		}
+	}
+	return headings
+}
+
+// Lint проверяет разметку: незакрытые блоки кода, ссылки без закрывающей скобки
+// или с пустым адресом и пустые заголовки. Строки внутри блоков кода и inline-код не проверяются
+func Lint(content string) []LintIssue {
+	var issues []LintIssue
+	fence, fenceLine := "", 0
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+
+		if m := fenceOpening.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence, fenceLine = m[1], i+1
+			case m[1][0] == fence[0] && len(m[1]) >= len(fence):
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+
+		if emptyHeading.MatchString(line) {
+			issues = append(issues, LintIssue{Line: i + 1, Message: "empty heading"})
+		}
+		line = codeSpan.ReplaceAllString(line, "")
+		if unclosedLink.MatchString(line) {
+			issues = append(issues, LintIssue{Line: i + 1, Message: "link is missing closing parenthesis"})
+		}
+		if emptyLink.MatchString(line) {
+			issues = append(issues, LintIssue{Line: i + 1, Message: "link has empty target"})
+		}
+	}
+	if fence != "" {
+		issues = append(issues, LintIssue{Line: fenceLine, Message: "code fence is never closed"})
+	}
+	return issues
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	content := "# Title\n" +
+		"#\n" +
+		"see [docs](https://go.dev\n" +
+		"`[code](`  and [empty]( )\n" +
+		"~~~\n" +
+		"[inside](fence\n" +
+		"~~~\n" +
+		"```go\n" +
+		"never closed"
+	want := []LintIssue{
+		{Line: 2, Message: "empty heading"},
+		{Line: 3, Message: "link is missing closing parenthesis"},
+		{Line: 4, Message: "link has empty target"},
+		{Line: 8, Message: "code fence is never closed"},
+	}
+	if got := Lint(content); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Lint = %+v, want %+v", got, want)
+	}
+
+	if got := Lint("# ok\n[link](https://go.dev)\n```\n#\n```"); got != nil {
+		t.Fatalf("Lint of clean content = %+v, want none", got)
+	}
+}
@@ -1,6 +1,9 @@
 package core
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Note struct {
 	ID        int64
@@ -9,3 +12,51 @@ type Note struct {
 	CreatedAt time.Time
 	UpdatedAt *time.Time
 }
+
+// LastModified - время последнего изменения; для ни разу не редактированной заметки - время создания
+func (n Note) LastModified() time.Time {
+	if n.UpdatedAt != nil {
+		return *n.UpdatedAt
+	}
+	return n.CreatedAt
+}
+
+// WordCount - число слов в тексте, разделенных пробельными символами
+func WordCount(text string) int {
+	return len(strings.Fields(text))
+}
+
+// Snapshot - неизменяемая копия заметки на момент создания
+type Snapshot struct {
+	Token     string
+	Note      Note
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+}
+
+// View - сохраненный под именем фильтр списка заметок (min_words и max_words из GET /notes) и порядок sort
+type View struct {
+	Name      string
+	Filter    map[string]string
+	CreatedAt time.Time
+}
+
+// NoteGroup - заметки, созданные в одном периоде
+type NoteGroup struct {
+	Period string
+	Notes  []Note
+}
+
+const (
+	AuditCreated  = "created"
+	AuditUpdated  = "updated"
+	AuditDeleted  = "deleted"
+	AuditRestored = "restored"
+)
+
+// AuditEntry - запись журнала действий над заметкой
+type AuditEntry struct {
+	Action string
+	Fields []string `json:",omitempty"`
+	At     time.Time
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// queryFields - поддерживаемые префиксы поиска вида field:value
+var queryFields = map[string]bool{"tag": true, "title": true}
+
+// FieldFilter - условие вида tag:work или title:"exact title"
+type FieldFilter struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// Query - разобранный поисковый запрос
+type Query struct {
+	Terms   []string      `json:"terms"`
+	Phrases []string      `json:"phrases"`
+	Fields  []FieldFilter `json:"fields"`
+}
+
+// QueryError - синтаксическая ошибка запроса; Pos - позиция в символах от 0
+type QueryError struct {
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// ParseQuery разбирает запрос на слова, фразы в кавычках и фильтры по полям.
+// Префикс с неизвестным полем (например, http:) считается частью обычного слова
+func ParseQuery(q string) (Query, error) {
+	query := Query{Terms: []string{}, Phrases: []string{}, Fields: []FieldFilter{}}
+	runes := []rune(q)
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		if runes[i] == '"' {
+			phrase, next, err := readPhrase(runes, i)
+			if err != nil {
+				return Query{}, err
+			}
+			query.Phrases = append(query.Phrases, phrase)
+			i = next
+			continue
+		}
+
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' && runes[i] != ':' {
+			i++
+		}
+		word := string(runes[start:i])
+
+		if i < len(runes) && runes[i] == ':' && queryFields[strings.ToLower(word)] {
+			i++
+			var value string
+			switch {
+			case i < len(runes) && runes[i] == '"':
+				phrase, next, err := readPhrase(runes, i)
+				if err != nil {
+					return Query{}, err
+				}
+				value, i = phrase, next
+			default:
+				valueStart := i
+				for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' {
+					i++
+				}
+				value = string(runes[valueStart:i])
+			}
+			if value == "" {
+				return Query{}, &QueryError{Pos: start, Msg: fmt.Sprintf("empty value for field %q", word)}
+			}
+			query.Fields = append(query.Fields, FieldFilter{Field: strings.ToLower(word), Value: value})
+			continue
+		}
+
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' {
+			i++
+		}
+		query.Terms = append(query.Terms, string(runes[start:i]))
+	}
+
+	return query, nil
+}
+
+// readPhrase читает фразу в кавычках, начиная с открывающей кавычки в позиции start
+func readPhrase(runes []rune, start int) (string, int, error) {
+	end := start + 1
+	for end < len(runes) && runes[end] != '"' {
+		end++
+	}
+	if end == len(runes) {
+		return "", 0, &QueryError{Pos: start, Msg: "unterminated quote"}
+	}
+
+	phrase := strings.TrimSpace(string(runes[start+1 : end]))
+	if phrase == "" {
+		return "", 0, &QueryError{Pos: start, Msg: "empty phrase"}
+	}
+	return phrase, end + 1, nil
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		q    string
+		want Query
+	}{
+		{
+			`tag:work "exact phrase" foo`,
+			Query{Terms: []string{"foo"}, Phrases: []string{"exact phrase"}, Fields: []FieldFilter{{"tag", "work"}}},
+		},
+		{
+			`TITLE:"Weekly plan" http://go.dev`,
+			Query{Terms: []string{"http://go.dev"}, Phrases: []string{}, Fields: []FieldFilter{{"title", "Weekly plan"}}},
+		},
+		{
+			"  ",
+			Query{Terms: []string{}, Phrases: []string{}, Fields: []FieldFilter{}},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ParseQuery(tt.q)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseQuery(%q) = %+v, %v; want %+v", tt.q, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		q   string
+		pos int
+		msg string
+	}{
+		{`foo "bar`, 4, "unterminated quote"},
+		{`a "  " b`, 2, "empty phrase"},
+		{`x tag: y`, 2, `empty value for field "tag"`},
+		{`тег title:"x`, 10, "unterminated quote"},
+	}
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.q)
+		var qerr *QueryError
+		if !errors.As(err, &qerr) || qerr.Pos != tt.pos || qerr.Msg != tt.msg {
+			t.Errorf("ParseQuery(%q): err = %v, want %q at position %d", tt.q, err, tt.msg, tt.pos)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// refPattern - ссылка на другую заметку в содержимом: [[42]] или [[0aZ...]] при кодировании ID
+	refPattern = regexp.MustCompile(`\[\[([0-9A-Za-z]+)\]\]`)
+
+	// includePattern - макрос включения содержимого другой заметки: {{include:42}}
+	includePattern = regexp.MustCompile(`\{\{include:([0-9A-Za-z]+)\}\}`)
+)
+
+// RefIDs переводит ID в ссылках и макросах в тот вид, который видит клиент, и обратно.
+// nil - десятичные числа, как хранятся ID
+type RefIDs interface {
+	Encode(id int64) string
+	Decode(s string) (int64, error)
+}
+
+func parseRefID(ids RefIDs, s string) (int64, error) {
+	if ids == nil {
+		return strconv.ParseInt(s, 10, 64)
+	}
+	return ids.Decode(s)
+}
+
+// FormatRefID - ID в том виде, в каком он записывается в ссылках и макросах
+func FormatRefID(ids RefIDs, id int64) string {
+	if ids == nil {
+		return strconv.FormatInt(id, 10)
+	}
+	return ids.Encode(id)
+}
+
+// DanglingRefs - ссылки заметки на заметки, которых больше нет
+type DanglingRefs struct {
+	NoteID  int64
+	Missing []int64
+}
+
+// References возвращает ID заметок, на которые ссылается содержимое, без повторов.
+// Ссылки, которые ids не может разобрать, пропускаются
+func References(content string, ids RefIDs) []int64 {
+	return collectIDs(refPattern, content, ids)
+}
+
+// ReplaceReferences заменяет ссылки [[id]] на строку, которую вернет replace;
+// если replace вернул false, ссылка остается как есть
+func ReplaceReferences(content string, ids RefIDs, replace func(id int64) (string, bool)) string {
+	return refPattern.ReplaceAllStringFunc(content, func(ref string) string {
+		id, err := parseRefID(ids, refPattern.FindStringSubmatch(ref)[1])
+		if err != nil {
+			return ref
+		}
+		if repl, ok := replace(id); ok {
+			return repl
+		}
+		return ref
+	})
+}
+
+// Includes возвращает ID заметок, подключаемых макросами {{include:id}}, без повторов
+func Includes(content string, ids RefIDs) []int64 {
+	return collectIDs(includePattern, content, ids)
+}
+
+func collectIDs(pattern *regexp.Regexp, content string, ids RefIDs) []int64 {
+	var found []int64
+	seen := make(map[int64]bool)
+	for _, m := range pattern.FindAllStringSubmatch(content, -1) {
+		id, err := parseRefID(ids, m[1])
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		found = append(found, id)
+	}
+	return found
+}
+
+// MarkIncludeDeleted заменяет макросы {{include:id}} для удаленной заметки пометкой
+func MarkIncludeDeleted(content string, id int64, ids RefIDs) string {
+	ref := FormatRefID(ids, id)
+	return strings.ReplaceAll(content, "{{include:"+ref+"}}", "[include:"+ref+" deleted]")
+}
+
+// ExpandIncludes подставляет вместо {{include:id}} содержимое заметок, которое вернет resolve.
+// Раскрывается один уровень: макросы внутри подставленного текста остаются как есть.
+// Включение заметки самой в себя и отсутствующие заметки заменяются пометками.
+func ExpandIncludes(selfID int64, content string, ids RefIDs, resolve func(id int64) (string, bool)) string {
+	return includePattern.ReplaceAllStringFunc(content, func(macro string) string {
+		ref := includePattern.FindStringSubmatch(macro)[1]
+		id, err := parseRefID(ids, ref)
+		if err != nil {
+			return macro
+		}
+		if id == selfID {
+			return fmt.Sprintf("[include:%s skipped: cycle]", ref)
+		}
+		included, ok := resolve(id)
+		if !ok {
+			return fmt.Sprintf("[include:%s not found]", ref)
+		}
+		return included
+	})
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExpandIncludes(t *testing.T) {
+	notes := map[int64]string{
+		2: "included {{include:3}}",
+	}
+	resolve := func(id int64) (string, bool) {
+		content, ok := notes[id]
+		return content, ok
+	}
+
+	got := ExpandIncludes(1, "a {{include:2}} b {{include:1}} c {{include:9}}", nil, resolve)
+	want := "a included {{include:3}} b [include:1 skipped: cycle] c [include:9 not found]"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestMarkIncludeDeleted(t *testing.T) {
+	got := MarkIncludeDeleted("{{include:4}} and {{include:42}}", 4, nil)
+	if want := "[include:4 deleted] and {{include:42}}"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// hexIDs - кодирование ID для проверки, что ссылки разбираются через RefIDs
+type hexIDs struct{}
+
+func (hexIDs) Encode(id int64) string { return "x" + strconv.FormatInt(id, 16) }
+
+func (hexIDs) Decode(s string) (int64, error) {
+	if !strings.HasPrefix(s, "x") {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.ParseInt(s[1:], 16, 64)
+}
+
+func TestRefsWithEncodedIDs(t *testing.T) {
+	content := "[[xa]] [[10]] [[xa]] {{include:xff}} {{include:7}}"
+	if got := References(content, hexIDs{}); len(got) != 1 || got[0] != 10 {
+		t.Fatalf("References = %v, want [10]", got)
+	}
+	if got := Includes(content, hexIDs{}); len(got) != 1 || got[0] != 255 {
+		t.Fatalf("Includes = %v, want [255]", got)
+	}
+	if got := References(content, nil); len(got) != 1 || got[0] != 10 {
+		t.Fatalf("References without encoding = %v, want [10]", got)
+	}
+
+	got := ExpandIncludes(1, content, hexIDs{}, func(id int64) (string, bool) { return "", false })
+	if want := "[[xa]] [[10]] [[xa]] [include:xff not found] {{include:7}}"; got != want {
+		t.Fatalf("ExpandIncludes = %q, want %q", got, want)
+	}
+	if got := MarkIncludeDeleted(content, 255, hexIDs{}); !strings.Contains(got, "[include:xff deleted]") {
+		t.Fatalf("MarkIncludeDeleted = %q", got)
+	}
+}
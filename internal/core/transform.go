@@ -0,0 +1,41 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ContentTransformer преобразует содержимое заметки перед сохранением
+type ContentTransformer interface {
+	Transform(content string) string
+}
+
+// TransformFunc позволяет использовать обычную функцию как ContentTransformer
+type TransformFunc func(content string) string
+
+func (f TransformFunc) Transform(content string) string {
+	return f(content)
+}
+
+// TrimTrailingWhitespace убирает пробелы и табы в конце каждой строки,
+// сохраняя пустые строки и окончания строк \r\n
+var TrimTrailingWhitespace = TransformFunc(func(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		cr := strings.HasSuffix(line, "\r")
+		line = strings.TrimRight(strings.TrimSuffix(line, "\r"), " \t")
+		if cr {
+			line += "\r"
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+})
+
+var blankRun = regexp.MustCompile(`\n(?:[ \t]*\r?\n){2,}`)
+
+// CollapseBlankLines обрезает пробельные символы по краям содержимого
+// и сводит подряд идущие пустые строки к одной
+var CollapseBlankLines = TransformFunc(func(content string) string {
+	return blankRun.ReplaceAllString(strings.TrimSpace(content), "\n\n")
+})
@@ -0,0 +1,11 @@
+package core
+
+import "testing"
+
+func TestTrimTrailingWhitespace(t *testing.T) {
+	got := TrimTrailingWhitespace.Transform("line one  \n\t\nline two\t \r\nend ")
+	want := "line one\n\nline two\r\nend"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,25 @@
+package httpx
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+// RequireAPIKey пропускает только запросы с верным ключом в заголовке X-API-Key
+func RequireAPIKey(key string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got := r.Header.Get(apiKeyHeader)
+			if subtle.ConstantTimeCompare([]byte(got), []byte(key)) != 1 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Invalid API key"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"example.com/notes-api/internal/backup"
+)
+
+type ReindexResponse struct {
+	Notes         int   `json:"notes"`
+	ContentHashes int   `json:"content_hashes"`
+	DurationMs    int64 `json:"duration_ms"`
+}
+
+type NormalizeResponse struct {
+	Changed int `json:"changed"`
+}
+
+type RestoreRequest struct {
+	File    string `json:"file"`
+	Confirm bool   `json:"confirm"`
+}
+
+type RestoreResponse struct {
+	File  string `json:"file"`
+	Notes int    `json:"notes"`
+}
+
+// AdminReindex перестраивает все производные индексы репозитория
+func (h *Handler) AdminReindex(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	notes, hashes := h.Repo.Reindex()
+
+	respondWithJSON(w, http.StatusOK, ReindexResponse{
+		Notes:         notes,
+		ContentHashes: hashes,
+		DurationMs:    time.Since(start).Milliseconds(),
+	})
+}
+
+// AdminListBackups возвращает резервные копии на диске с датой и числом заметок
+func (h *Handler) AdminListBackups(w http.ResponseWriter, r *http.Request) {
+	if h.Backups == nil {
+		respondWithError(w, http.StatusNotFound, "Backups are not configured")
+		return
+	}
+
+	infos, err := h.Backups.List()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list backups")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, infos)
+}
+
+// AdminRestore заменяет все заметки содержимым резервной копии; требует "confirm": true
+func (h *Handler) AdminRestore(w http.ResponseWriter, r *http.Request) {
+	if h.Backups == nil {
+		respondWithError(w, http.StatusNotFound, "Backups are not configured")
+		return
+	}
+
+	var req RestoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if !req.Confirm {
+		respondWithError(w, http.StatusBadRequest, "Restore replaces all notes; set confirm to true")
+		return
+	}
+
+	notes, err := h.Backups.Load(req.File)
+	if err != nil {
+		switch {
+		case errors.Is(err, backup.ErrBackupNotFound):
+			respondWithError(w, http.StatusBadRequest, "Backup not found")
+		case errors.Is(err, backup.ErrBackupCorrupt):
+			respondWithError(w, http.StatusBadRequest, "Backup is corrupt")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to read backup")
+		}
+		return
+	}
+
+	h.Repo.ReplaceAll(notes)
+
+	respondWithJSON(w, http.StatusOK, RestoreResponse{File: req.File, Notes: len(notes)})
+}
+
+// AdminNormalize заново применяет текущие правила нормализации ко всем заметкам:
+// обрезает пробелы в названии, приводит переводы строк к \n и прогоняет
+// содержимое через настроенные преобразования
+func (h *Handler) AdminNormalize(w http.ResponseWriter, r *http.Request) {
+	changed := h.Repo.NormalizeAll(func(title, content string) (string, string) {
+		if trimmed := strings.TrimSpace(title); trimmed != "" {
+			title = trimmed
+		}
+		content = strings.ReplaceAll(content, "\r\n", "\n")
+		return title, h.prepareContent(content)
+	})
+
+	respondWithJSON(w, http.StatusOK, NormalizeResponse{Changed: changed})
+}
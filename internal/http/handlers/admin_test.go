@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"example.com/notes-api/internal/backup"
+	"example.com/notes-api/internal/config"
+	"example.com/notes-api/internal/core"
+	"example.com/notes-api/internal/http/handlers"
+)
+
+const testAdminKey = "test-admin-key"
+
+func withAdminKey(h *handlers.Handler, cfg *config.Config) {
+	cfg.AdminAPIKey = testAdminKey
+}
+
+func TestAdminReindex(t *testing.T) {
+	api := newTestAPI(t, withAdminKey)
+	api.createNote("a", "same")
+	api.createNote("b", "same")
+
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/admin/reindex", ""), http.StatusUnauthorized)
+
+	rec := api.do(http.MethodPost, "/api/v1/admin/reindex", "", "X-API-Key", testAdminKey)
+	expectStatus(t, rec, http.StatusOK)
+
+	var resp struct {
+		Notes         int `json:"notes"`
+		ContentHashes int `json:"content_hashes"`
+	}
+	decodeJSON(t, rec, &resp)
+	if resp.Notes != 2 || resp.ContentHashes != 1 {
+		t.Fatalf("reindex = %+v, want 2 notes and 1 hash", resp)
+	}
+}
+
+func TestAdminRoutesDisabledWithoutKey(t *testing.T) {
+	api := newTestAPI(t, nil)
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/admin/reindex", ""), http.StatusNotFound)
+}
+
+func TestAdminRestore(t *testing.T) {
+	api := newTestAPI(t, func(h *handlers.Handler, cfg *config.Config) {
+		withAdminKey(h, cfg)
+		h.Backups = &backup.Backuper{Repo: h.Repo, Dir: t.TempDir(), Interval: time.Hour, Keep: 5}
+	})
+	kept := api.createNote("kept", "original")
+	edited := api.createNote("edited", "before")
+	before := map[string]exportedNote{kept: getNote(t, api, kept), edited: getNote(t, api, edited)}
+
+	name, err := api.handler.Backups.WriteBackup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectStatus(t, api.do(http.MethodPatch, "/api/v1/notes/"+edited, `{"content":"after"}`), http.StatusOK)
+	expectStatus(t, api.do(http.MethodDelete, "/api/v1/notes/"+kept, ""), http.StatusOK)
+	added := api.createNote("added", "new")
+
+	body := fmt.Sprintf(`{"file":%q,"confirm":false}`, name)
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/admin/restore", body, "X-API-Key", testAdminKey), http.StatusBadRequest)
+
+	body = fmt.Sprintf(`{"file":%q,"confirm":true}`, name)
+	rec := api.do(http.MethodPost, "/api/v1/admin/restore", body, "X-API-Key", testAdminKey)
+	expectStatus(t, rec, http.StatusOK)
+
+	for id, want := range before {
+		if got := getNote(t, api, id); !sameNote(got, want) {
+			t.Errorf("note %s after restore = %+v, want %+v", id, got, want)
+		}
+	}
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/"+added, ""), http.StatusNotFound)
+
+	body = `{"file":"notes-missing.json","confirm":true}`
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/admin/restore", body, "X-API-Key", testAdminKey), http.StatusBadRequest)
+}
+
+func TestAdminNormalize(t *testing.T) {
+	api := newTestAPI(t, func(h *handlers.Handler, cfg *config.Config) {
+		withAdminKey(h, cfg)
+		h.Transformers = append(h.Transformers, core.TrimTrailingWhitespace)
+	})
+	clean := api.createNote("clean", "ok")
+	// заметки из старой версии хранилища, записанные до нормализации
+	created := time.Now()
+	for _, n := range []core.Note{
+		{ID: 10, Title: "  spaced title  ", Content: "a  \r\nb", CreatedAt: created},
+		{ID: 11, Title: "   ", Content: "x", CreatedAt: created},
+	} {
+		if _, err := api.handler.Repo.Restore(n, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := api.do(http.MethodPost, "/api/v1/admin/normalize", "", "X-API-Key", testAdminKey)
+	expectStatus(t, rec, http.StatusOK)
+	var resp struct {
+		Changed int `json:"changed"`
+	}
+	decodeJSON(t, rec, &resp)
+	if resp.Changed != 1 {
+		t.Fatalf("changed = %d, want 1", resp.Changed)
+	}
+
+	if n := getNote(t, api, "10"); n.Title != "spaced title" || n.Content != "a\nb" || n.UpdatedAt == nil {
+		t.Fatalf("normalized note = %+v", n)
+	}
+	// пустое после обрезки название не заменяется
+	if n := getNote(t, api, "11"); n.Title != "   " || n.UpdatedAt != nil {
+		t.Fatalf("note with a blank title = %+v, want it unchanged", n)
+	}
+	if n := getNote(t, api, clean); n.UpdatedAt != nil {
+		t.Fatalf("clean note = %+v, want it unchanged", n)
+	}
+}
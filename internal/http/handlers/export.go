@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"example.com/notes-api/internal/config"
+	"example.com/notes-api/internal/core"
+	"example.com/notes-api/internal/repo"
+)
+
+type ExportMetadata struct {
+	ID        interface{} `json:"id"`
+	Title     string      `json:"title"`
+	File      string      `json:"file"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt *time.Time  `json:"updated_at"`
+}
+
+// ExportTarGz отдает все заметки архивом tar.gz: по .md-файлу на заметку и metadata.json.
+// Архив пишется в ответ потоком, без сборки в памяти. Фильтры - как в GetAllNotes.
+func (h *Handler) ExportTarGz(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseNoteFilter(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	notes, err := h.Repo.GetAll()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get notes")
+		return
+	}
+	notes = filter.apply(notes)
+
+	if err := sortNotes(notes, r.URL.Query().Get("sort")); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="notes.tar.gz"`)
+	w.WriteHeader(http.StatusOK)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	used := make(map[string]bool, len(notes))
+	metadata := make([]ExportMetadata, 0, len(notes))
+	for _, note := range notes {
+		name := slugify(note.Title)
+		for used[name] {
+			name += fmt.Sprintf("-%v", h.idOut(note.ID))
+		}
+		used[name] = true
+		name += ".md"
+
+		// клиент уже получил 200, поэтому при ошибке записи остается только оборвать архив
+		if err := writeTarFile(tw, name, []byte(noteMarkdown(note)), note.LastModified()); err != nil {
+			log.Printf("export tar.gz: %s: %v", name, err)
+			return
+		}
+
+		metadata = append(metadata, ExportMetadata{
+			ID:        h.idOut(note.ID),
+			Title:     note.Title,
+			File:      name,
+			CreatedAt: note.CreatedAt,
+			UpdatedAt: note.UpdatedAt,
+		})
+	}
+
+	meta, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		log.Printf("export tar.gz: metadata.json: %v", err)
+		return
+	}
+	if err := writeTarFile(tw, "metadata.json", meta, now); err != nil {
+		log.Printf("export tar.gz: metadata.json: %v", err)
+		return
+	}
+
+	// Close дописывает конец архива и gzip-трейлер; без них клиент получит обрезанный файл
+	if err := tw.Close(); err != nil {
+		log.Printf("export tar.gz: close tar: %v", err)
+		return
+	}
+	if err := gz.Close(); err != nil {
+		log.Printf("export tar.gz: close gzip: %v", err)
+	}
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// slugify делает из названия имя файла: буквы и цифры в нижнем регистре через дефис
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	slug := strings.TrimSuffix(b.String(), "-")
+	if slug == "" {
+		return "note"
+	}
+	return slug
+}
+
+type ImportError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+type ImportResponse struct {
+	Created     int           `json:"created"`
+	Overwritten int           `json:"overwritten"`
+	Skipped     int           `json:"skipped"`
+	Errors      []ImportError `json:"errors"`
+}
+
+// importedNote - заметка из export.json. ID приходит в клиентском виде (строка при
+// включенном кодировании, иначе число) и перекрывает поле ID из core.Note.
+type importedNote struct {
+	ID json.RawMessage
+	core.Note
+}
+
+// ExportJSON отдает все заметки с ID и временными метками, чтобы их можно было
+// восстановить через ImportNotes с ?preserve=true. ID отдаются в том же виде,
+// что и в остальных ответах, поэтому выгрузка не раскрывает внутренние номера.
+func (h *Handler) ExportJSON(w http.ResponseWriter, r *http.Request) {
+	notes, err := h.Repo.GetAll()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get notes")
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="notes.json"`)
+	respondWithJSON(w, http.StatusOK, h.views(notes))
+}
+
+// parseExportedID разбирает ID заметки из export.json, как parseID - из URL
+func (h *Handler) parseExportedID(raw json.RawMessage) (int64, error) {
+	if h.IDCodec != nil {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return 0, err
+		}
+		return h.IDCodec.Decode(s)
+	}
+
+	var id int64
+	if err := json.Unmarshal(raw, &id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// ImportNotes создает заметки из JSON-массива. С ?preserve=true сохраняются исходные ID,
+// CreatedAt и UpdatedAt; занятые ID обрабатываются по ?on_conflict=skip|overwrite
+// (по умолчанию - из конфигурации).
+func (h *Handler) ImportNotes(w http.ResponseWriter, r *http.Request) {
+	preserve := r.URL.Query().Get("preserve") == "true"
+
+	// политика конфликтов нужна только при preserve: без него ID выдает хранилище
+	overwrite := false
+	if preserve {
+		policy := r.URL.Query().Get("on_conflict")
+		if policy == "" {
+			policy = h.ImportConflict
+		}
+		switch policy {
+		case "", config.ImportSkip:
+		case config.ImportOverwrite:
+			overwrite = true
+		default:
+			respondWithError(w, http.StatusBadRequest, "on_conflict must be one of: skip, overwrite")
+			return
+		}
+	}
+
+	var items []importedNote
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	resp := ImportResponse{Errors: []ImportError{}}
+	for i, item := range items {
+		n := item.Note
+		if strings.TrimSpace(n.Title) == "" {
+			resp.Errors = append(resp.Errors, ImportError{Index: i, Error: "Title is required"})
+			continue
+		}
+
+		if !preserve {
+			n.Content = h.prepareContent(n.Content)
+			if _, err := h.Repo.Create(n); err != nil {
+				resp.Errors = append(resp.Errors, ImportError{Index: i, Error: "Failed to create note"})
+				continue
+			}
+			resp.Created++
+			continue
+		}
+
+		if len(item.ID) == 0 || n.CreatedAt.IsZero() {
+			resp.Errors = append(resp.Errors, ImportError{Index: i, Error: "ID and CreatedAt are required with preserve"})
+			continue
+		}
+		id, err := h.parseExportedID(item.ID)
+		if err != nil || id <= 0 {
+			resp.Errors = append(resp.Errors, ImportError{Index: i, Error: "Invalid note ID"})
+			continue
+		}
+		n.ID = id
+
+		replaced, err := h.Repo.Restore(n, overwrite)
+		switch {
+		case err == repo.ErrNoteExists:
+			resp.Skipped++
+		case err != nil:
+			resp.Errors = append(resp.Errors, ImportError{Index: i, Error: "Failed to restore note"})
+		case replaced:
+			resp.Overwritten++
+		default:
+			resp.Created++
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+type ExportDiffResponse struct {
+	OnlyInStore    []interface{} `json:"only_in_store"`
+	OnlyInUpload   []interface{} `json:"only_in_upload"`
+	ContentDiffers []interface{} `json:"content_differs"`
+}
+
+// DiffExport сравнивает загруженную выгрузку export.json с текущим хранилищем по ID.
+// Содержимое сравнивается после нормализации, как в индексе хешей.
+func (h *Handler) DiffExport(w http.ResponseWriter, r *http.Request) {
+	var items []importedNote
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	uploaded := make(map[int64]string, len(items))
+	for i, item := range items {
+		id, err := h.parseExportedID(item.ID)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid note ID at index %d", i))
+			return
+		}
+		uploaded[id] = repo.ContentHash(item.Content)
+	}
+
+	notes, err := h.Repo.GetAll()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get notes")
+		return
+	}
+
+	var onlyInStore, onlyInUpload, contentDiffers []int64
+	stored := make(map[int64]bool, len(notes))
+	for _, n := range notes {
+		stored[n.ID] = true
+		hash, exists := uploaded[n.ID]
+		switch {
+		case !exists:
+			onlyInStore = append(onlyInStore, n.ID)
+		case hash != repo.ContentHash(n.Content):
+			contentDiffers = append(contentDiffers, n.ID)
+		}
+	}
+	for id := range uploaded {
+		if !stored[id] {
+			onlyInUpload = append(onlyInUpload, id)
+		}
+	}
+	sort.Slice(onlyInUpload, func(i, j int) bool { return onlyInUpload[i] < onlyInUpload[j] })
+
+	respondWithJSON(w, http.StatusOK, ExportDiffResponse{
+		OnlyInStore:    h.idsOut(onlyInStore),
+		OnlyInUpload:   h.idsOut(onlyInUpload),
+		ContentDiffers: h.idsOut(contentDiffers),
+	})
+}
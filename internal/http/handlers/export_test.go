@@ -0,0 +1,254 @@
+package handlers_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/notes-api/internal/config"
+	"example.com/notes-api/internal/http/handlers"
+)
+
+// readTarGz возвращает содержимое файлов архива по именам и порядок их следования
+func readTarGz(t *testing.T, data io.Reader) (map[string]string, []string) {
+	t.Helper()
+
+	gz, err := gzip.NewReader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+
+	files := make(map[string]string)
+	var order []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, order
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = string(body)
+		order = append(order, hdr.Name)
+	}
+}
+
+func TestExportTarGz(t *testing.T) {
+	api := newTestAPI(t, nil)
+	api.createNote("Hello, World!", "hi")
+	api.createNote("hello world", "")
+	api.createNote("???", "x")
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/export.tar.gz", "")
+	expectStatus(t, rec, http.StatusOK)
+
+	files, order := readTarGz(t, rec.Body)
+	wantOrder := []string{"hello-world.md", "hello-world-2.md", "note.md", "metadata.json"}
+	if len(order) != len(wantOrder) {
+		t.Fatalf("entries = %v, want %v", order, wantOrder)
+	}
+	for i, name := range wantOrder {
+		if order[i] != name {
+			t.Fatalf("entries = %v, want %v", order, wantOrder)
+		}
+	}
+
+	if got := files["hello-world.md"]; got != "# Hello, World!\n\nhi\n" {
+		t.Errorf("hello-world.md = %q", got)
+	}
+
+	var meta []struct {
+		ID    interface{} `json:"id"`
+		Title string      `json:"title"`
+		File  string      `json:"file"`
+	}
+	if err := json.Unmarshal([]byte(files["metadata.json"]), &meta); err != nil {
+		t.Fatal(err)
+	}
+	if len(meta) != 3 || meta[1].Title != "hello world" || meta[1].File != "hello-world-2.md" {
+		t.Fatalf("metadata = %+v", meta)
+	}
+}
+
+type exportedNote struct {
+	ID        interface{}
+	Title     string
+	Content   string
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+func getNote(t *testing.T, api *testAPI, id string) exportedNote {
+	t.Helper()
+	rec := api.do(http.MethodGet, "/api/v1/notes/"+id, "")
+	expectStatus(t, rec, http.StatusOK)
+	var n exportedNote
+	decodeJSON(t, rec, &n)
+	return n
+}
+
+func sameNote(a, b exportedNote) bool {
+	if idString(a.ID) != idString(b.ID) || a.Title != b.Title || a.Content != b.Content || !a.CreatedAt.Equal(b.CreatedAt) {
+		return false
+	}
+	if a.UpdatedAt == nil || b.UpdatedAt == nil {
+		return a.UpdatedAt == nil && b.UpdatedAt == nil
+	}
+	return a.UpdatedAt.Equal(*b.UpdatedAt)
+}
+
+func TestExportPreserveImportRoundTrip(t *testing.T) {
+	source := newTestAPI(t, nil)
+	first := source.createNote("first", "one")
+	second := source.createNote("second", "two")
+	expectStatus(t, source.do(http.MethodPatch, "/api/v1/notes/"+second, `{"content":"two, edited"}`), http.StatusOK)
+	export := source.do(http.MethodGet, "/api/v1/notes/export.json", "").Body.String()
+
+	type importResult struct {
+		Created     int `json:"created"`
+		Overwritten int `json:"overwritten"`
+		Skipped     int `json:"skipped"`
+	}
+
+	// в целевом хранилище ID 1 уже занят другой заметкой
+	target := newTestAPI(t, nil)
+	target.createNote("local", "")
+
+	var res importResult
+	rec := target.do(http.MethodPost, "/api/v1/notes/import?preserve=true", export)
+	expectStatus(t, rec, http.StatusOK)
+	decodeJSON(t, rec, &res)
+	if res != (importResult{Created: 1, Skipped: 1}) {
+		t.Fatalf("skip policy: %+v, want 1 created and 1 skipped", res)
+	}
+	if got := getNote(t, target, first); got.Title != "local" {
+		t.Fatalf("skipped note was replaced: %+v", got)
+	}
+	if got, want := getNote(t, target, second), getNote(t, source, second); !sameNote(got, want) {
+		t.Fatalf("imported note = %+v, want %+v", got, want)
+	}
+
+	rec = target.do(http.MethodPost, "/api/v1/notes/import?preserve=true&on_conflict=overwrite", export)
+	expectStatus(t, rec, http.StatusOK)
+	decodeJSON(t, rec, &res)
+	if res != (importResult{Overwritten: 2}) {
+		t.Fatalf("overwrite policy: %+v, want 2 overwritten", res)
+	}
+	for _, id := range []string{first, second} {
+		if got, want := getNote(t, target, id), getNote(t, source, id); !sameNote(got, want) {
+			t.Fatalf("note %s = %+v, want %+v", id, got, want)
+		}
+	}
+
+	// новые заметки не получают ID, занятые при импорте
+	if id := target.createNote("new", ""); id != "3" {
+		t.Fatalf("next ID = %s, want 3", id)
+	}
+}
+
+func TestDiffExport(t *testing.T) {
+	api := newTestAPI(t, nil)
+	api.createNote("same", "text")
+	api.createNote("changed", "old")
+	api.createNote("missing", "")
+
+	upload := `[
+		{"ID": 1, "Title": "same", "Content": "text\r\n"},
+		{"ID": 2, "Title": "changed", "Content": "new"},
+		{"ID": 7, "Title": "extra", "Content": ""}
+	]`
+	rec := api.do(http.MethodPost, "/api/v1/notes/diff-export", upload)
+	expectStatus(t, rec, http.StatusOK)
+
+	var diff struct {
+		OnlyInStore    []int64 `json:"only_in_store"`
+		OnlyInUpload   []int64 `json:"only_in_upload"`
+		ContentDiffers []int64 `json:"content_differs"`
+	}
+	decodeJSON(t, rec, &diff)
+	if len(diff.OnlyInStore) != 1 || diff.OnlyInStore[0] != 3 ||
+		len(diff.OnlyInUpload) != 1 || diff.OnlyInUpload[0] != 7 ||
+		len(diff.ContentDiffers) != 1 || diff.ContentDiffers[0] != 2 {
+		t.Fatalf("diff = %+v, want only_in_store [3], only_in_upload [7], content_differs [2]", diff)
+	}
+
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/notes/diff-export", `{}`), http.StatusBadRequest)
+}
+
+func TestImportConflictPolicyOnlyWithPreserve(t *testing.T) {
+	api := newTestAPI(t, func(h *handlers.Handler, cfg *config.Config) {
+		h.ImportConflict = ""
+	})
+	existing := api.createNote("existing", "")
+
+	var resp struct {
+		Created int `json:"created"`
+		Skipped int `json:"skipped"`
+	}
+	rec := api.do(http.MethodPost, "/api/v1/notes/import?on_conflict=x", `[{"Title":"plain"}]`)
+	expectStatus(t, rec, http.StatusOK)
+	decodeJSON(t, rec, &resp)
+	if resp.Created != 1 {
+		t.Fatalf("plain import = %+v, want the stray on_conflict ignored", resp)
+	}
+
+	body := `[{"ID":` + existing + `,"Title":"restored","CreatedAt":"2024-01-01T00:00:00Z"}]`
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/notes/import?preserve=true&on_conflict=x", body), http.StatusBadRequest)
+
+	// без политики в конфигурации занятый ID пропускается
+	rec = api.do(http.MethodPost, "/api/v1/notes/import?preserve=true", body)
+	expectStatus(t, rec, http.StatusOK)
+	decodeJSON(t, rec, &resp)
+	if resp.Skipped != 1 || getNote(t, api, existing).Title != "existing" {
+		t.Fatalf("preserve import with the default policy = %+v, want the existing note skipped", resp)
+	}
+}
+
+// failingWriter принимает первые limit байт тела, а дальше возвращает ошибку
+type failingWriter struct {
+	header http.Header
+	limit  int
+}
+
+func (w *failingWriter) Header() http.Header { return w.header }
+func (w *failingWriter) WriteHeader(int)     {}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		return 0, errors.New("connection reset")
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestExportTarGzLogsWriteFailure(t *testing.T) {
+	api := newTestAPI(t, nil)
+	api.createNote("a", "text")
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/notes/export.tar.gz", nil)
+	// gzip сразу пишет только 10-байтовый заголовок, а сжатые данные и трейлер - в Close
+	api.router.ServeHTTP(&failingWriter{header: http.Header{}, limit: 10}, req)
+
+	if !strings.Contains(logged.String(), "export tar.gz: close gzip: connection reset") {
+		t.Fatalf("log = %q, want the gzip close failure", logged.String())
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"example.com/notes-api/internal/core"
+	"example.com/notes-api/internal/repo"
+)
+
+// noteFilter - фильтры списка заметок из query-параметров
+type noteFilter struct {
+	minWords, maxWords int
+	hasMin, hasMax     bool
+}
+
+// parseNoteFilter читает фильтры из query; ошибка пригодна для ответа клиенту
+func parseNoteFilter(q url.Values) (noteFilter, error) {
+	var f noteFilter
+	var err error
+
+	if f.minWords, f.hasMin, err = parseNonNegative(q, "min_words"); err != nil {
+		return noteFilter{}, err
+	}
+	if f.maxWords, f.hasMax, err = parseNonNegative(q, "max_words"); err != nil {
+		return noteFilter{}, err
+	}
+	if f.hasMin && f.hasMax && f.minWords > f.maxWords {
+		return noteFilter{}, errors.New("min_words cannot be greater than max_words")
+	}
+
+	return f, nil
+}
+
+func (f noteFilter) match(n core.Note) bool {
+	if f.hasMin || f.hasMax {
+		words := core.WordCount(n.Content)
+		if f.hasMin && words < f.minWords {
+			return false
+		}
+		if f.hasMax && words > f.maxWords {
+			return false
+		}
+	}
+	return true
+}
+
+func (f noteFilter) apply(notes []core.Note) []core.Note {
+	filtered := notes[:0]
+	for _, n := range notes {
+		if f.match(n) {
+			filtered = append(filtered, n)
+		}
+	}
+	return filtered
+}
+
+// next возвращает следующую подходящую заметку курсора; false - заметки закончились
+func (f noteFilter) next(c *repo.NoteCursor) (core.Note, bool) {
+	for {
+		n, ok := c.Next()
+		if !ok || f.match(n) {
+			return n, ok
+		}
+	}
+}
+
+func parseNonNegative(q url.Values, key string) (int, bool, error) {
+	v := q.Get(key)
+	if v == "" {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, false, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+	return n, true, nil
+}
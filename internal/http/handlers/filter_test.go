@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// seedWordCounts создает заметки с 1, 3, 5 и 8 словами, названные по числу слов
+func seedWordCounts(api *testAPI) {
+	for _, content := range []string{
+		"one",
+		"one two three",
+		"one two three four five",
+		"one two three four five six seven eight",
+	} {
+		api.createNote(strings.Repeat("w", len(strings.Fields(content))), content)
+	}
+}
+
+func TestWordCountRangeFilter(t *testing.T) {
+	api := newTestAPI(t, nil)
+	seedWordCounts(api)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"min_words=3&max_words=5", []string{"www", "wwwww"}},
+		{"min_words=5", []string{"wwwww", "wwwwwwww"}},
+		{"max_words=1", []string{"w"}},
+		{"min_words=9", []string{}},
+	}
+	for _, tt := range tests {
+		rec := api.do(http.MethodGet, "/api/v1/notes?"+tt.query, "")
+		expectStatus(t, rec, http.StatusOK)
+
+		var notes []struct{ Title string }
+		decodeJSON(t, rec, &notes)
+		var got []string
+		for _, n := range notes {
+			got = append(got, n.Title)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"min_words=-1", "max_words=x", "min_words=5&max_words=3"} {
+		expectStatus(t, api.do(http.MethodGet, "/api/v1/notes?"+query, ""), http.StatusBadRequest)
+	}
+}
+
+func TestWordCountFilterOnExports(t *testing.T) {
+	api := newTestAPI(t, nil)
+	seedWordCounts(api)
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/combined.md?min_words=3&max_words=5", "")
+	expectStatus(t, rec, http.StatusOK)
+	if md := rec.Body.String(); strings.Count(md, "\n# ") != 1 || !strings.HasPrefix(md, "# www\n") || !strings.Contains(md, "# wwwww\n") {
+		t.Fatalf("combined.md = %q, want only the 3- and 5-word notes", md)
+	}
+
+	rec = api.do(http.MethodGet, "/api/v1/notes/export.tar.gz?max_words=1", "")
+	expectStatus(t, rec, http.StatusOK)
+	_, order := readTarGz(t, rec.Body)
+	if strings.Join(order, ",") != "w.md,metadata.json" {
+		t.Fatalf("archive entries = %v, want only the 1-word note", order)
+	}
+
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/combined.md?min_words=x", ""), http.StatusBadRequest)
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/export.tar.gz?min_words=x", ""), http.StatusBadRequest)
+}
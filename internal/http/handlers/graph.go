@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"example.com/notes-api/internal/config"
+	"example.com/notes-api/internal/core"
+	"github.com/go-chi/chi/v5"
+)
+
+const maxGraphDepth = 2
+
+type GraphNode struct {
+	ID        interface{} `json:"id"`
+	Title     string      `json:"title"`
+	Outbound  []GraphNode `json:"outbound,omitempty"`
+	Backlinks []GraphNode `json:"backlinks,omitempty"`
+}
+
+type GraphResponse struct {
+	Note      noteView    `json:"note"`
+	Outbound  []GraphNode `json:"outbound"`
+	Backlinks []GraphNode `json:"backlinks"`
+}
+
+// linkGraph - ссылки [[id]] между существующими заметками в обе стороны
+type linkGraph struct {
+	notes     map[int64]core.Note
+	outbound  map[int64][]int64
+	backlinks map[int64][]int64
+}
+
+func buildLinkGraph(notes []core.Note, refIDs core.RefIDs) linkGraph {
+	g := linkGraph{
+		notes:     make(map[int64]core.Note, len(notes)),
+		outbound:  make(map[int64][]int64),
+		backlinks: make(map[int64][]int64),
+	}
+	for _, n := range notes {
+		g.notes[n.ID] = n
+	}
+	// notes отсортированы по ID, поэтому списки ссылок получаются упорядоченными
+	for _, n := range notes {
+		for _, ref := range core.References(n.Content, refIDs) {
+			if _, exists := g.notes[ref]; !exists || ref == n.ID {
+				continue
+			}
+			g.outbound[n.ID] = append(g.outbound[n.ID], ref)
+			g.backlinks[ref] = append(g.backlinks[ref], n.ID)
+		}
+	}
+	for _, ids := range g.outbound {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	return g
+}
+
+// GetNoteGraph возвращает заметку с исходящими ссылками [[id]] и обратными ссылками.
+// ?depth=1 (по умолчанию) раскрывает связи соседей еще на один уровень, максимум - 2.
+func (h *Handler) GetNoteGraph(w http.ResponseWriter, r *http.Request) {
+	id, err := h.parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
+		return
+	}
+
+	depth := 1
+	if depthStr := r.URL.Query().Get("depth"); depthStr != "" {
+		depth, err = strconv.Atoi(depthStr)
+		if err != nil || depth < 0 {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Depth must be between 0 and %d", maxGraphDepth))
+			return
+		}
+		if depth > maxGraphDepth {
+			h.limitExceeded(w, config.LimitGraphDepth, fmt.Sprintf("Depth must be between 0 and %d", maxGraphDepth))
+			return
+		}
+	}
+
+	notes, err := h.Repo.GetAll()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get notes")
+		return
+	}
+
+	g := buildLinkGraph(notes, h.refIDs())
+	note, exists := g.notes[id]
+	if !exists {
+		respondWithError(w, http.StatusNotFound, "Note not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, GraphResponse{
+		Note:      h.view(note),
+		Outbound:  h.graphNodes(g, g.outbound[id], depth),
+		Backlinks: h.graphNodes(g, g.backlinks[id], depth),
+	})
+}
+
+func (h *Handler) graphNodes(g linkGraph, ids []int64, depth int) []GraphNode {
+	nodes := make([]GraphNode, 0, len(ids))
+	for _, id := range ids {
+		node := GraphNode{ID: h.idOut(id), Title: g.notes[id].Title}
+		if depth > 0 {
+			node.Outbound = h.graphNodes(g, g.outbound[id], depth-1)
+			node.Backlinks = h.graphNodes(g, g.backlinks[id], depth-1)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
@@ -0,0 +1,52 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+)
+
+type graphNode struct {
+	ID        interface{} `json:"id"`
+	Title     string      `json:"title"`
+	Outbound  []graphNode `json:"outbound"`
+	Backlinks []graphNode `json:"backlinks"`
+}
+
+func TestGetNoteGraph(t *testing.T) {
+	api := newTestAPI(t, nil)
+	c := api.createNote("c", "leaf")
+	b := api.createNote("b", "to [["+c+"]]")
+	a := api.createNote("a", "to [["+b+"]] and missing [[999]]")
+
+	var graph struct {
+		Note      exportedNote `json:"note"`
+		Outbound  []graphNode  `json:"outbound"`
+		Backlinks []graphNode  `json:"backlinks"`
+	}
+	rec := api.do(http.MethodGet, "/api/v1/notes/"+b+"/graph?depth=0", "")
+	expectStatus(t, rec, http.StatusOK)
+	decodeJSON(t, rec, &graph)
+	if idString(graph.Note.ID) != b || len(graph.Outbound) != 1 || len(graph.Backlinks) != 1 {
+		t.Fatalf("graph of %s = %+v", b, graph)
+	}
+	if idString(graph.Outbound[0].ID) != c || idString(graph.Backlinks[0].ID) != a || graph.Outbound[0].Backlinks != nil {
+		t.Fatalf("depth 0: outbound %+v, backlinks %+v", graph.Outbound, graph.Backlinks)
+	}
+
+	rec = api.do(http.MethodGet, "/api/v1/notes/"+a+"/graph?depth=1", "")
+	expectStatus(t, rec, http.StatusOK)
+	decodeJSON(t, rec, &graph)
+	if len(graph.Outbound) != 1 || len(graph.Backlinks) != 0 {
+		t.Fatalf("graph of %s = %+v, want only the link to %s", a, graph, b)
+	}
+	next := graph.Outbound[0]
+	if idString(next.ID) != b || len(next.Outbound) != 1 || idString(next.Outbound[0].ID) != c ||
+		len(next.Backlinks) != 1 || idString(next.Backlinks[0].ID) != a {
+		t.Fatalf("depth 1: %+v, want %s linking to %s and back to %s", next, b, c, a)
+	}
+
+	for _, depth := range []string{"-1", "3", "x"} {
+		expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/"+a+"/graph?depth="+depth, ""), http.StatusBadRequest)
+	}
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/999/graph", ""), http.StatusNotFound)
+}
@@ -0,0 +1,106 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/notes-api/internal/config"
+	httpx "example.com/notes-api/internal/http"
+	"example.com/notes-api/internal/http/handlers"
+	"example.com/notes-api/internal/repo"
+)
+
+// testAPI - роутер, собранный как в cmd/api, поверх чистого in-memory хранилища
+type testAPI struct {
+	t       *testing.T
+	router  http.Handler
+	handler *handlers.Handler
+}
+
+// newTestAPI собирает API; configure (может быть nil) меняет Handler и Config до сборки роутера
+func newTestAPI(t *testing.T, configure func(h *handlers.Handler, cfg *config.Config)) *testAPI {
+	t.Helper()
+
+	cfg := config.Config{
+		RequestIDFormat:  config.RequestIDUUID,
+		RequestIDMaxLen:  64,
+		ImportConflict:   config.ImportSkip,
+		DeleteDependents: config.DependentsIgnore,
+	}
+	h := &handlers.Handler{
+		Repo:             repo.NewNoteRepoMem(),
+		Snapshots:        repo.NewSnapshotRepoMem(),
+		ImportConflict:   cfg.ImportConflict,
+		DeleteDependents: cfg.DeleteDependents,
+	}
+	if configure != nil {
+		configure(h, &cfg)
+	}
+
+	return &testAPI{t: t, router: httpx.NewRouter(h, cfg), handler: h}
+}
+
+// do выполняет запрос; headers - пары имя, значение
+func (a *testAPI) do(method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	a.t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// createNote создает заметку и возвращает ее ID в том виде, в каком он идет в URL
+func (a *testAPI) createNote(title, content string) string {
+	a.t.Helper()
+
+	body, _ := json.Marshal(map[string]string{"Title": title, "Content": content})
+	rec := a.do(http.MethodPost, "/api/v1/notes", string(body))
+	if rec.Code != http.StatusCreated {
+		a.t.Fatalf("create %q: status %d, body %s", title, rec.Code, rec.Body)
+	}
+
+	var created struct{ ID interface{} }
+	decodeJSON(a.t, rec, &created)
+	return idString(created.ID)
+}
+
+// idString приводит ID из JSON (число или строку) к виду для URL
+func idString(id interface{}) string {
+	if f, ok := id.(float64); ok {
+		return fmt.Sprint(int64(f))
+	}
+	return fmt.Sprint(id)
+}
+
+func decodeJSON(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode response %q: %v", rec.Body, err)
+	}
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d; body %s", rec.Code, want, rec.Body)
+	}
+}
+
+// mustTime разбирает время в формате RFC 3339
+func mustTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	ts, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"example.com/notes-api/internal/core"
+)
+
+// noteView - заметка в ответе API. Если включено кодирование ID, поле ID - строка,
+// иначе число, как в core.Note. Поле ID перекрывает одноименное поле core.Note.
+type noteView struct {
+	ID interface{}
+	core.Note
+}
+
+// noteSummaryView - заметка в списке без содержимого (?content=false):
+// Content всегда null, а ContentURL указывает, где взять заметку целиком
+type noteSummaryView struct {
+	ID interface{}
+	core.Note
+	Content    *string
+	ContentURL string
+}
+
+// parseID разбирает ID заметки из URL: закодированную строку при включенном кодировании, иначе число
+func (h *Handler) parseID(s string) (int64, error) {
+	if h.IDCodec != nil {
+		return h.IDCodec.Decode(s)
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// idOut - ID в том виде, в каком он отдается клиенту
+func (h *Handler) idOut(id int64) interface{} {
+	if h.IDCodec != nil {
+		return h.IDCodec.Encode(id)
+	}
+	return id
+}
+
+// refIDs - формат ID в ссылках [[id]] и макросах {{include:id}}: тот же, что в URL и ответах.
+// Возвращает nil-интерфейс, а не интерфейс с nil-указателем, если кодирование выключено
+func (h *Handler) refIDs() core.RefIDs {
+	if h.IDCodec == nil {
+		return nil
+	}
+	return h.IDCodec
+}
+
+// idsOut переводит список ID в клиентский вид; пустой список - [], а не null
+func (h *Handler) idsOut(ids []int64) []interface{} {
+	out := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, h.idOut(id))
+	}
+	return out
+}
+
+func (h *Handler) view(n core.Note) noteView {
+	return noteView{ID: h.idOut(n.ID), Note: n}
+}
+
+func (h *Handler) views(notes []core.Note) []noteView {
+	out := make([]noteView, 0, len(notes))
+	for _, n := range notes {
+		out = append(out, h.view(n))
+	}
+	return out
+}
+
+// summary строит элемент списка без содержимого; base - путь коллекции, например /api/v1/notes
+func (h *Handler) summary(n core.Note, base string) noteSummaryView {
+	id := h.idOut(n.ID)
+	return noteSummaryView{
+		ID:         id,
+		Note:       n,
+		ContentURL: fmt.Sprintf("%s/%v", strings.TrimSuffix(base, "/"), id),
+	}
+}
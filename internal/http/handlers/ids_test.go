@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"example.com/notes-api/internal/config"
+	"example.com/notes-api/internal/http/handlers"
+	"example.com/notes-api/internal/idcodec"
+)
+
+func withIDCodec(h *handlers.Handler, cfg *config.Config) {
+	h.IDCodec = idcodec.New("test salt")
+}
+
+func TestEncodedIDRoundTrip(t *testing.T) {
+	api := newTestAPI(t, withIDCodec)
+	api.createNote("first", "")
+	id := api.createNote("second", "")
+
+	if len(id) != 13 || strings.Trim(id, "0123456789") == "" {
+		t.Fatalf("ID %q does not look encoded", id)
+	}
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/"+id, "")
+	expectStatus(t, rec, http.StatusOK)
+	var note struct {
+		ID    string
+		Title string
+	}
+	decodeJSON(t, rec, &note)
+	if note.ID != id || note.Title != "second" {
+		t.Fatalf("got %+v, want the second note with ID %s", note, id)
+	}
+
+	tampered := []byte(id)
+	tampered[3] ^= 1
+	for _, bad := range []string{string(tampered), "2", id + "x"} {
+		expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/"+bad, ""), http.StatusBadRequest)
+	}
+}
+
+func TestExportsDoNotLeakInternalIDs(t *testing.T) {
+	api := newTestAPI(t, withIDCodec)
+	api.createNote("same", "")
+	second := api.createNote("same", "")
+
+	_, order := readTarGz(t, api.do(http.MethodGet, "/api/v1/notes/export.tar.gz", "").Body)
+	if len(order) != 3 || order[1] != "same-"+second+".md" {
+		t.Fatalf("archive entries = %v, want the duplicate named after its encoded ID", order)
+	}
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/export.json", "")
+	expectStatus(t, rec, http.StatusOK)
+	var exported []struct{ ID interface{} }
+	decodeJSON(t, rec, &exported)
+	if len(exported) != 2 || exported[1].ID != second {
+		t.Fatalf("export.json IDs = %+v, want encoded strings", exported)
+	}
+}
+
+func TestPreserveImportWithEncodedIDs(t *testing.T) {
+	source := newTestAPI(t, withIDCodec)
+	source.createNote("a", "")
+	id := source.createNote("b", "text")
+	export := source.do(http.MethodGet, "/api/v1/notes/export.json", "").Body.String()
+
+	target := newTestAPI(t, withIDCodec)
+	rec := target.do(http.MethodPost, "/api/v1/notes/import?preserve=true", export)
+	expectStatus(t, rec, http.StatusOK)
+	var resp struct {
+		Created int `json:"created"`
+	}
+	decodeJSON(t, rec, &resp)
+	if resp.Created != 2 {
+		t.Fatalf("import = %s, want 2 created", rec.Body)
+	}
+	expectStatus(t, target.do(http.MethodGet, "/api/v1/notes/"+id, ""), http.StatusOK)
+
+	// ID, подделанный в выгрузке, отклоняется
+	var notes []map[string]interface{}
+	json.Unmarshal([]byte(export), &notes)
+	notes[1]["ID"] = "2"
+	tampered, _ := json.Marshal(notes[1:])
+	rec = newTestAPI(t, withIDCodec).do(http.MethodPost, "/api/v1/notes/import?preserve=true", string(tampered))
+	var failed struct {
+		Created int `json:"created"`
+		Errors  []struct {
+			Error string `json:"error"`
+		} `json:"errors"`
+	}
+	decodeJSON(t, rec, &failed)
+	if failed.Created != 0 || len(failed.Errors) != 1 || failed.Errors[0].Error != "Invalid note ID" {
+		t.Fatalf("import with a raw ID = %s, want it rejected", rec.Body)
+	}
+
+	expectStatus(t, target.do(http.MethodPost, "/api/v1/notes/diff-export", string(tampered)), http.StatusBadRequest)
+}
+
+func TestEncodedIDsInLinksAndIncludes(t *testing.T) {
+	api := newTestAPI(t, withIDCodec)
+	part := api.createNote("part", "shared")
+	whole := api.createNote("whole", "see [["+part+"]]\n{{include:"+part+"}}\nraw [[1]]")
+
+	if got := getNote(t, api, whole+"?expand=true").Content; got != "see [["+part+"]]\nshared\nraw [[1]]" {
+		t.Fatalf("expanded content = %q, want the include resolved by its encoded ID", got)
+	}
+
+	var graph struct {
+		Outbound  []graphNode `json:"outbound"`
+		Backlinks []graphNode `json:"backlinks"`
+	}
+	decodeJSON(t, api.do(http.MethodGet, "/api/v1/notes/"+part+"/graph", ""), &graph)
+	if len(graph.Backlinks) != 1 || graph.Backlinks[0].ID != whole || len(graph.Outbound) != 0 {
+		t.Fatalf("graph of %s = %+v, want a backlink from %s", part, graph, whole)
+	}
+
+	rec := api.do(http.MethodDelete, "/api/v1/notes/"+part+"?dependents=block", "")
+	expectStatus(t, rec, http.StatusConflict)
+	expectStatus(t, api.do(http.MethodDelete, "/api/v1/notes/"+part+"?dependents=mark", ""), http.StatusOK)
+	if got := getNote(t, api, whole).Content; !strings.Contains(got, "[include:"+part+" deleted]") {
+		t.Fatalf("content after deleting the include = %q", got)
+	}
+
+	rec = api.do(http.MethodPost, "/api/v1/notes/repair-links?apply=true&mode=mark", "")
+	expectStatus(t, rec, http.StatusOK)
+	var repair struct {
+		Notes []struct {
+			NoteID  interface{}   `json:"note_id"`
+			Missing []interface{} `json:"missing"`
+		} `json:"notes"`
+	}
+	decodeJSON(t, rec, &repair)
+	if len(repair.Notes) != 1 || repair.Notes[0].NoteID != whole || len(repair.Notes[0].Missing) != 1 || repair.Notes[0].Missing[0] != part {
+		t.Fatalf("repair = %+v, want the encoded ID %s reported missing", repair, part)
+	}
+	if got := getNote(t, api, whole).Content; !strings.Contains(got, "[[deleted:"+part+"]]") {
+		t.Fatalf("content after repair = %q", got)
+	}
+}
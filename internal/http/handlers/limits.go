@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// softLimit сообщает, настроен ли лимит как мягкий: превышение отвечает 429 с Retry-After, а не 400
+func (h *Handler) softLimit(limit string) bool {
+	return h.SoftLimits[limit]
+}
+
+func (h *Handler) retryAfterSeconds() int {
+	return int(h.RetryAfter.Seconds())
+}
+
+// limitExceeded отвечает на превышение лимита: 400 по умолчанию или 429 с Retry-After,
+// если лимит настроен как мягкий
+func (h *Handler) limitExceeded(w http.ResponseWriter, limit, message string) {
+	if !h.softLimit(limit) {
+		respondWithError(w, http.StatusBadRequest, message)
+		return
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(h.retryAfterSeconds()))
+	respondWithError(w, http.StatusTooManyRequests, message)
+}
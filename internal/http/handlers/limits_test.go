@@ -0,0 +1,88 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/notes-api/internal/config"
+	"example.com/notes-api/internal/http/handlers"
+)
+
+func withSoftLimits(limits ...string) func(h *handlers.Handler, cfg *config.Config) {
+	return func(h *handlers.Handler, cfg *config.Config) {
+		h.SoftLimits = make(map[string]bool)
+		for _, limit := range limits {
+			h.SoftLimits[limit] = true
+		}
+		h.RetryAfter = 30 * time.Second
+	}
+}
+
+func TestLimitsHardAndSoft(t *testing.T) {
+	seen := make(map[string]time.Time, 1001)
+	for i := 1; i <= 1001; i++ {
+		seen[fmt.Sprint(i)] = time.Now()
+	}
+	delta, _ := json.Marshal(seen)
+
+	requests := []struct {
+		limit, method, target, body string
+	}{
+		{config.LimitOldest, http.MethodGet, "/api/v1/notes/oldest?limit=500", ""},
+		{config.LimitDeltaItems, http.MethodPost, "/api/v1/notes/delta", string(delta)},
+		{config.LimitGraphDepth, http.MethodGet, "/api/v1/notes/1/graph?depth=5", ""},
+		{config.LimitImportLine, http.MethodPost, "/api/v1/notes/stream-import", `{"Title":"` + strings.Repeat("x", 2<<20) + `"}`},
+	}
+
+	hard := newTestAPI(t, nil)
+	hard.createNote("a", "")
+	soft := newTestAPI(t, withSoftLimits(config.LimitOldest, config.LimitDeltaItems, config.LimitGraphDepth, config.LimitImportLine))
+	soft.createNote("a", "")
+
+	for _, req := range requests {
+		rec := hard.do(req.method, req.target, req.body)
+		if req.limit == config.LimitImportLine {
+			// в жестком режиме длинная строка - ошибка в результатах, импорт продолжается
+			results := decodeNDJSON(t, rec.Body)
+			if rec.Code != http.StatusOK || len(results) != 1 || results[0].Status != "error" {
+				t.Errorf("%s hard: %d %+v, want an inline error", req.limit, rec.Code, results)
+			}
+		} else if rec.Code != http.StatusBadRequest || rec.Header().Get("Retry-After") != "" {
+			t.Errorf("%s hard: status %d, Retry-After %q; want 400 without Retry-After",
+				req.limit, rec.Code, rec.Header().Get("Retry-After"))
+		}
+
+		rec = soft.do(req.method, req.target, req.body)
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" {
+			t.Errorf("%s soft: status %d, Retry-After %q; want 429 with Retry-After 30",
+				req.limit, rec.Code, rec.Header().Get("Retry-After"))
+		}
+	}
+
+	// ошибочный параметр остается 400 и при мягком лимите
+	expectStatus(t, soft.do(http.MethodGet, "/api/v1/notes/1/graph?depth=-1", ""), http.StatusBadRequest)
+}
+
+func TestStreamImportSoftLineLimitMidStream(t *testing.T) {
+	api := newTestAPI(t, withSoftLimits(config.LimitImportLine))
+
+	huge := `{"Title":"` + strings.Repeat("x", 2<<20) + `"}`
+	body := "{\"Title\":\"before\"}\n" + huge + "\n{\"Title\":\"after\"}\n"
+	rec := api.do(http.MethodPost, "/api/v1/notes/stream-import", body)
+	expectStatus(t, rec, http.StatusOK)
+
+	results := decodeNDJSON(t, rec.Body)
+	if len(results) != 2 || results[0].Status != "created" {
+		t.Fatalf("results = %+v, want import to stop on line 2", results)
+	}
+	if r := results[1]; r.Line != 2 || r.Status != "throttled" || r.RetryAfter != 30 {
+		t.Fatalf("oversized line result = %+v, want throttled with retry_after 30", r)
+	}
+	if notes, _ := api.handler.Repo.GetAll(); len(notes) != 1 {
+		t.Fatalf("stored %d notes, want 1", len(notes))
+	}
+}
@@ -1,28 +1,145 @@
 package handlers
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"example.com/notes-api/internal/backup"
+	"example.com/notes-api/internal/config"
 	"example.com/notes-api/internal/core"
+	"example.com/notes-api/internal/idcodec"
 	"example.com/notes-api/internal/repo"
 	"github.com/go-chi/chi/v5"
 )
 
+const (
+	// maxImportLineSize ограничивает размер одной строки NDJSON-импорта
+	maxImportLineSize = 1 << 20
+
+	defaultOldestLimit = 20
+	maxOldestLimit     = 100
+
+	maxDeltaItems = 1000
+)
+
 type Handler struct {
-	Repo *repo.NoteRepoMem
+	Repo      *repo.NoteRepoMem
+	Snapshots *repo.SnapshotRepoMem
+	Views     *repo.ViewRepoMem
+
+	// Backups - резервные копии на диске; nil, если каталог не настроен
+	Backups *backup.Backuper
+
+	// IDCodec кодирует ID в URL, ответах и ссылках [[id]]/{{include:id}} в содержимом; nil - обычные числовые ID
+	IDCodec *idcodec.Codec
+
+	// Transformers по порядку применяются к содержимому при каждой записи
+	Transformers []core.ContentTransformer
+
+	// ImportConflict - политика импорта по умолчанию для занятых ID (config.ImportSkip или config.ImportOverwrite)
+	ImportConflict string
+
+	// AllowEmptyPatch - PATCH без полей возвращает заметку без изменений (200) вместо 400
+	AllowEmptyPatch bool
+
+	// DeleteDependents - политика удаления по умолчанию для подключаемых заметок (config.DependentsIgnore и др.)
+	DeleteDependents string
+
+	// SoftLimits - имена лимитов (config.LimitDeltaItems и др.), превышение которых
+	// отвечает 429 с Retry-After; остальные лимиты отвечают 400
+	SoftLimits map[string]bool
+	RetryAfter time.Duration
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+type DependentsErrorResponse struct {
+	Error      string        `json:"error"`
+	Dependents []interface{} `json:"dependents"`
+}
+
+type QueryErrorResponse struct {
+	Error    string `json:"error"`
+	Position int    `json:"position"`
+}
+
 type SuccessResponse struct {
 	Message string `json:"message"`
 }
 
+type StreamImportResult struct {
+	Line       int         `json:"line"`
+	Status     string      `json:"status"`
+	ID         interface{} `json:"id,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	RetryAfter int         `json:"retry_after,omitempty"`
+}
+
+type NoteSizeResponse struct {
+	TitleBytes   int `json:"title_bytes"`
+	ContentBytes int `json:"content_bytes"`
+	TotalBytes   int `json:"total_bytes"`
+}
+
+type StorageResponse struct {
+	Notes int `json:"notes"`
+	NoteSizeResponse
+}
+
+type RepairLinksResponse struct {
+	Applied      bool           `json:"applied"`
+	NotesTouched int            `json:"notes_touched"`
+	Notes        []danglingView `json:"notes"`
+}
+
+// danglingView - core.DanglingRefs с ID в клиентском виде
+type danglingView struct {
+	NoteID  interface{}   `json:"note_id"`
+	Missing []interface{} `json:"missing"`
+}
+
+type NoteLinks struct {
+	Note noteView `json:"note"`
+	URLs []string `json:"urls"`
+}
+
+type ChecksumResponse struct {
+	Algorithm string `json:"algorithm"`
+	Hex       string `json:"hex"`
+}
+
+type TOCEntry struct {
+	ID       interface{}    `json:"id"`
+	Title    string         `json:"title"`
+	Headings []core.Heading `json:"headings"`
+}
+
+type LintResult struct {
+	ID     interface{}      `json:"id"`
+	Title  string           `json:"title"`
+	Issues []core.LintIssue `json:"issues"`
+}
+
+type DeltaResult struct {
+	Status string    `json:"status"`
+	Note   *noteView `json:"note,omitempty"`
+}
+
 type UpdateNoteRequest struct {
 	Title   *string `json:"title"`
 	Content *string `json:"content"`
@@ -42,6 +159,7 @@ func (h *Handler) CreateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	n.Content = h.prepareContent(n.Content)
 	id, err := h.Repo.Create(n)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create note")
@@ -54,18 +172,25 @@ func (h *Handler) CreateNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondWithJSON(w, http.StatusCreated, createdNote)
+	respondWithJSON(w, http.StatusCreated, h.view(*createdNote))
 }
 
-// GetNote возвращает заметку по ID
+// GetNote возвращает заметку по ID; с ?expand=true макросы {{include:id}} раскрываются
 func (h *Handler) GetNote(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+	id, err := h.parseID(chi.URLParam(r, "id"))
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
 		return
 	}
 
+	var proj projection
+	if expr := r.URL.Query().Get("projection"); expr != "" {
+		if proj, err = parseProjection(expr); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
 	note, err := h.Repo.GetByID(id)
 	if err != nil {
 		if err == repo.ErrNoteNotFound {
@@ -76,90 +201,748 @@ func (h *Handler) GetNote(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, note)
+	// контрольная сумма всегда считается по хранимому содержимому, как в GetNoteChecksum
+	w.Header().Set("X-Content-SHA256", contentSHA256(note.Content))
+
+	if r.URL.Query().Get("expand") == "true" {
+		note.Content = core.ExpandIncludes(note.ID, note.Content, h.refIDs(), func(includeID int64) (string, bool) {
+			included, err := h.Repo.GetByID(includeID)
+			if err != nil {
+				return "", false
+			}
+			return included.Content, true
+		})
+	}
+
+	if proj != nil {
+		respondWithJSON(w, http.StatusOK, h.project(proj, *note))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, h.view(*note))
 }
 
-// GetAllNotes возвращает все заметки
+// GetAllNotes возвращает все заметки. Заметки читаются из хранилища по одной
+// по мере записи ответа, поэтому память не растет с их числом
 func (h *Handler) GetAllNotes(w http.ResponseWriter, r *http.Request) {
+	var proj projection
+	if expr := r.URL.Query().Get("projection"); expr != "" {
+		var err error
+		if proj, err = parseProjection(expr); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	filter, err := parseNoteFilter(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	withContent := true
+	switch r.URL.Query().Get("content") {
+	case "", "true":
+	case "false":
+		withContent = false
+	default:
+		respondWithError(w, http.StatusBadRequest, "Content must be true or false")
+		return
+	}
+	if !withContent && proj != nil {
+		respondWithError(w, http.StatusBadRequest, "Parameter content=false cannot be combined with projection")
+		return
+	}
+
+	maxItems, capped := preferMaxItems(r)
+	if capped {
+		// Preference-Applied уходит до тела, поэтому сначала выясняем, урежет ли
+		// предпочтение ответ: обход останавливается на maxItems+1 подходящей заметке
+		matched := 0
+		h.Repo.Each(func(n core.Note) bool {
+			if filter.match(n) {
+				matched++
+			}
+			return matched <= maxItems
+		})
+		if matched > maxItems {
+			w.Header().Set("Preference-Applied", "max-items="+strconv.Itoa(maxItems))
+		}
+	}
+
+	item := func(n core.Note) interface{} { return h.view(n) }
+	switch {
+	case proj != nil:
+		item = func(n core.Note) interface{} { return h.project(proj, n) }
+	case !withContent:
+		item = func(n core.Note) interface{} { return h.summary(n, r.URL.Path) }
+	}
+
+	cursor := h.Repo.Cursor()
+	sent := 0
+	streamJSONArray(w, func() (interface{}, bool) {
+		if capped && sent >= maxItems {
+			return nil, false
+		}
+		n, ok := filter.next(cursor)
+		if !ok {
+			return nil, false
+		}
+		sent++
+		return item(n), true
+	})
+}
+
+// GetTimeline возвращает заметки, сгруппированные по периоду создания
+func (h *Handler) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	granularity := r.URL.Query().Get("granularity")
+	if granularity == "" {
+		granularity = repo.GranularityDay
+	}
+
+	loc, err := parseTZOffset(r.URL.Query().Get("tz"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid tz offset")
+		return
+	}
+
+	groups, err := h.Repo.GroupByPeriod(granularity, loc)
+	if err != nil {
+		if err == repo.ErrInvalidGranularity {
+			respondWithError(w, http.StatusBadRequest, "Granularity must be one of: day, week, month")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get timeline")
+		}
+		return
+	}
+
+	type groupView struct {
+		Period string
+		Notes  []noteView
+	}
+	result := make([]groupView, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, groupView{Period: g.Period, Notes: h.views(g.Notes)})
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// GetCombinedMarkdown отдает все заметки одним Markdown-документом; фильтры - как в GetAllNotes
+func (h *Handler) GetCombinedMarkdown(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseNoteFilter(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	notes, err := h.Repo.GetAll()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get notes")
+		return
+	}
+	notes = filter.apply(notes)
+
+	if err := sortNotes(notes, r.URL.Query().Get("sort")); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var b strings.Builder
+	for i, note := range notes {
+		if i > 0 {
+			b.WriteString("\n---\n\n")
+		}
+		b.WriteString(noteMarkdown(note))
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="notes.md"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(b.String()))
+}
+
+// GetTOC возвращает оглавление по всем заметкам: название и Markdown-заголовки каждой
+func (h *Handler) GetTOC(w http.ResponseWriter, r *http.Request) {
+	notes, err := h.Repo.GetAll()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get notes")
+		return
+	}
+
+	if err := sortNotes(notes, r.URL.Query().Get("sort")); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	toc := make([]TOCEntry, 0, len(notes))
+	for _, note := range notes {
+		headings := core.Headings(note.Content)
+		if headings == nil {
+			headings = []core.Heading{}
+		}
+		toc = append(toc, TOCEntry{ID: h.idOut(note.ID), Title: note.Title, Headings: headings})
+	}
+
+	respondWithJSON(w, http.StatusOK, toc)
+}
+
+// LintNotes возвращает заметки с ошибками Markdown-разметки и найденные проблемы
+func (h *Handler) LintNotes(w http.ResponseWriter, r *http.Request) {
+	notes, err := h.Repo.GetAll()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get notes")
+		return
+	}
+
+	results := make([]LintResult, 0)
+	for _, note := range notes {
+		if issues := core.Lint(note.Content); len(issues) > 0 {
+			results = append(results, LintResult{ID: h.idOut(note.ID), Title: note.Title, Issues: issues})
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+// ExplainSearch показывает, как разбирается поисковый запрос ?q=..., не выполняя его
+func (h *Handler) ExplainSearch(w http.ResponseWriter, r *http.Request) {
+	query, err := core.ParseQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		var qerr *core.QueryError
+		if errors.As(err, &qerr) {
+			respondWithJSON(w, http.StatusBadRequest, QueryErrorResponse{Error: qerr.Error(), Position: qerr.Pos})
+			return
+		}
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, query)
+}
+
+// GetOldestNotes возвращает заметки, которые дольше всех не обновлялись
+func (h *Handler) GetOldestNotes(w http.ResponseWriter, r *http.Request) {
+	limit := defaultOldestLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 1 {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Limit must be between 1 and %d", maxOldestLimit))
+			return
+		}
+		if n > maxOldestLimit {
+			h.limitExceeded(w, config.LimitOldest, fmt.Sprintf("Limit must be between 1 and %d", maxOldestLimit))
+			return
+		}
+		limit = n
+	}
+
+	notes, err := h.Repo.LeastRecentlyUpdated(limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get notes")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, h.views(notes))
+}
+
+// GetUntouchedNotes возвращает заметки, которые ни разу не редактировались
+func (h *Handler) GetUntouchedNotes(w http.ResponseWriter, r *http.Request) {
+	notes, err := h.Repo.Untouched()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get notes")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, h.views(notes))
+}
+
+// GetNotesWithLinks возвращает заметки с внешними ссылками; ?domain= оставляет ссылки только на этот домен
+func (h *Handler) GetNotesWithLinks(w http.ResponseWriter, r *http.Request) {
+	domain := strings.TrimSpace(r.URL.Query().Get("domain"))
+
 	notes, err := h.Repo.GetAll()
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to get notes")
 		return
 	}
 
-	if notes == nil {
-		notes = []core.Note{}
+	result := []NoteLinks{}
+	for _, note := range notes {
+		var urls []string
+		for _, u := range core.ExtractURLs(note.Content) {
+			if domain == "" || core.URLHasDomain(u, domain) {
+				urls = append(urls, u)
+			}
+		}
+		if len(urls) > 0 {
+			result = append(result, NoteLinks{Note: h.view(note), URLs: urls})
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// GetDelta сверяет состояние клиента: для каждого ID с меткой последнего просмотра
+// сообщает unchanged, updated (вместе с заметкой), deleted или not_found
+func (h *Handler) GetDelta(w http.ResponseWriter, r *http.Request) {
+	var seen map[string]time.Time
+	if err := json.NewDecoder(r.Body).Decode(&seen); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON: expected an object of note ID to RFC 3339 timestamp")
+		return
+	}
+
+	if len(seen) > maxDeltaItems {
+		h.limitExceeded(w, config.LimitDeltaItems, fmt.Sprintf("At most %d notes per request", maxDeltaItems))
+		return
+	}
+
+	// ключи проверяются до поиска заметок и в отсортированном порядке,
+	// чтобы при нескольких неверных ID ошибка не зависела от порядка обхода map
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ids := make(map[string]int64, len(seen))
+	for _, key := range keys {
+		id, err := h.parseID(key)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid note ID: "+key)
+			return
+		}
+		ids[key] = id
+	}
+
+	result := make(map[string]DeltaResult, len(seen))
+	for _, key := range keys {
+		id, since := ids[key], seen[key]
+		note, err := h.Repo.GetByID(id)
+		if err == repo.ErrNoteNotFound {
+			// журнал переживает удаление заметки, поэтому по нему отличаем удаленные от несуществующих
+			if _, auditErr := h.Repo.AuditTrail(id); auditErr == nil {
+				result[key] = DeltaResult{Status: "deleted"}
+			} else {
+				result[key] = DeltaResult{Status: "not_found"}
+			}
+			continue
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get note")
+			return
+		}
+
+		if note.LastModified().After(since) {
+			view := h.view(*note)
+			result[key] = DeltaResult{Status: "updated", Note: &view}
+		} else {
+			result[key] = DeltaResult{Status: "unchanged"}
+		}
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// GetNotesByHash возвращает заметки с указанным SHA-256 нормализованного содержимого
+func (h *Handler) GetNotesByHash(w http.ResponseWriter, r *http.Request) {
+	hash := strings.ToLower(chi.URLParam(r, "hash"))
+	if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+		respondWithError(w, http.StatusBadRequest, "Hash must be a hex-encoded SHA-256")
+		return
+	}
+
+	notes, err := h.Repo.FindByContentHash(hash)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get notes")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, h.views(notes))
+}
+
+// StreamImport создает заметки из NDJSON-потока по мере чтения строк.
+// Результат по каждой строке сразу пишется в ответ тоже в формате NDJSON.
+// Ошибки строк (в том числе слишком длинные строки) не прерывают импорт,
+// кроме режима ?strict=true, где импорт останавливается на первой ошибке,
+// и мягкого лимита config.LimitImportLine, который останавливает импорт на длинной строке.
+func (h *Handler) StreamImport(w http.ResponseWriter, r *http.Request) {
+	strict := r.URL.Query().Get("strict") == "true"
+
+	rc := http.NewResponseController(w)
+	// читаем тело и пишем ответ одновременно
+	rc.EnableFullDuplex()
+
+	// статус уходит вместе с первым результатом: если отправить его до чтения тела,
+	// сервер не ответит клиенту с Expect: 100-continue и тело не придет
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	encoder := json.NewEncoder(w)
+	reader := bufio.NewReaderSize(r.Body, 64*1024)
+
+	started := false
+	for line := 1; ; line++ {
+		raw, tooLong, err := readNDJSONLine(reader, maxImportLineSize)
+		if err != nil && err != io.EOF {
+			encoder.Encode(StreamImportResult{Line: line, Status: "error", Error: "Failed to read line"})
+			return
+		}
+		if err == io.EOF && len(raw) == 0 && !tooLong {
+			return
+		}
+
+		var result StreamImportResult
+		switch {
+		case tooLong:
+			message := fmt.Sprintf("Line exceeds %d bytes", maxImportLineSize)
+			if h.softLimit(config.LimitImportLine) {
+				// мягкий лимит останавливает импорт: до первого результата - обычным 429,
+				// после - результатом "throttled", потому что статус ответа уже отправлен
+				if !started {
+					h.limitExceeded(w, config.LimitImportLine, message)
+					return
+				}
+				encoder.Encode(StreamImportResult{Line: line, Status: "throttled", Error: message, RetryAfter: h.retryAfterSeconds()})
+				return
+			}
+			result = StreamImportResult{Status: "error", Error: message}
+		case len(bytes.TrimSpace(raw)) == 0:
+			continue
+		default:
+			result = h.importNDJSONLine(bytes.TrimSpace(raw))
+		}
+		result.Line = line
+		encoder.Encode(result)
+		rc.Flush()
+		started = true
+
+		if (strict && result.Error != "") || err == io.EOF {
+			return
+		}
+	}
+}
+
+// readNDJSONLine читает строку до \n включительно. Строка длиннее max дочитывается
+// до конца без сохранения и возвращается с tooLong, чтобы импорт мог продолжиться
+// со следующей строки. io.EOF означает, что строка последняя.
+func readNDJSONLine(reader *bufio.Reader, max int) (line []byte, tooLong bool, err error) {
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLong {
+			if len(line)+len(chunk) > max {
+				line, tooLong = nil, true
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+		if err != bufio.ErrBufferFull {
+			return line, tooLong, err
+		}
+	}
+}
+
+// importNDJSONLine создает заметку из одной строки NDJSON-импорта
+func (h *Handler) importNDJSONLine(raw []byte) StreamImportResult {
+	var n core.Note
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return StreamImportResult{Status: "error", Error: "Invalid JSON"}
+	}
+
+	if strings.TrimSpace(n.Title) == "" {
+		return StreamImportResult{Status: "error", Error: "Title is required"}
+	}
+
+	n.Content = h.prepareContent(n.Content)
+	id, err := h.Repo.Create(n)
+	if err != nil {
+		return StreamImportResult{Status: "error", Error: "Failed to create note"}
+	}
+
+	return StreamImportResult{Status: "created", ID: h.idOut(id)}
+}
+
+// RepairLinks ищет ссылки [[id]] на удаленные заметки.
+// По умолчанию только отчет; ?apply=true вырезает ссылки, а с ?mode=mark помечает их.
+func (h *Handler) RepairLinks(w http.ResponseWriter, r *http.Request) {
+	apply := r.URL.Query().Get("apply") == "true"
+
+	mark := false
+	switch r.URL.Query().Get("mode") {
+	case "", "strip":
+	case "mark":
+		mark = true
+	default:
+		respondWithError(w, http.StatusBadRequest, "Mode must be one of: strip, mark")
+		return
+	}
+
+	dangling, err := h.Repo.RepairDanglingRefs(apply, mark, h.refIDs())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to repair links")
+		return
+	}
+
+	notes := make([]danglingView, 0, len(dangling))
+	for _, d := range dangling {
+		notes = append(notes, danglingView{NoteID: h.idOut(d.NoteID), Missing: h.idsOut(d.Missing)})
 	}
 
-	respondWithJSON(w, http.StatusOK, notes)
+	respondWithJSON(w, http.StatusOK, RepairLinksResponse{
+		Applied:      apply,
+		NotesTouched: len(dangling),
+		Notes:        notes,
+	})
 }
 
 // PatchNote - частичное обновление (PATCH)
 func (h *Handler) PatchNote(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+	id, err := h.parseID(chi.URLParam(r, "id"))
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
 		return
 	}
 
-	var update UpdateNoteRequest
-	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+	updates, err := h.decodePatch(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if update.Title == nil && update.Content == nil {
-		respondWithError(w, http.StatusBadRequest, "No fields to update")
+	// пустой PATCH при AllowEmptyPatch ничего не меняет, даже UpdatedAt
+	if len(updates) > 0 {
+		err = h.Repo.UpdatePartial(id, updates)
+	}
+	if err != nil {
+		if err == repo.ErrNoteNotFound {
+			respondWithError(w, http.StatusNotFound, "Note not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update note")
+		}
 		return
 	}
 
-	if update.Title != nil && strings.TrimSpace(*update.Title) == "" {
-		respondWithError(w, http.StatusBadRequest, "Title cannot be empty")
+	updatedNote, err := h.Repo.GetByID(id)
+	if err != nil {
+		if err == repo.ErrNoteNotFound {
+			respondWithError(w, http.StatusNotFound, "Note not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to retrieve updated note")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, h.view(*updatedNote))
+}
+
+// PreviewPatch показывает, какой станет заметка после PATCH, ничего не сохраняя
+func (h *Handler) PreviewPatch(w http.ResponseWriter, r *http.Request) {
+	id, err := h.parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
+		return
+	}
+
+	updates, err := h.decodePatch(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var preview *core.Note
+	if len(updates) > 0 {
+		preview, err = h.Repo.PreviewPartial(id, updates)
+	} else {
+		preview, err = h.Repo.GetByID(id)
+	}
+	if err != nil {
+		if err == repo.ErrNoteNotFound {
+			respondWithError(w, http.StatusNotFound, "Note not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to preview note")
+		}
 		return
 	}
 
+	respondWithJSON(w, http.StatusOK, h.view(*preview))
+}
+
+// decodePatch разбирает и проверяет PATCH-запрос, возвращая изменения для репозитория.
+// Если тело пустое, поля берутся из query-параметров: PATCH /notes/1?title=New
+func (h *Handler) decodePatch(r *http.Request) (map[string]interface{}, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.New("Failed to read request body")
+	}
+
+	var update UpdateNoteRequest
+	if len(bytes.TrimSpace(body)) == 0 {
+		update = patchFromQuery(r.URL.Query())
+	} else if err := json.Unmarshal(body, &update); err != nil {
+		return nil, errors.New("Invalid JSON")
+	}
+
+	if update.Title == nil && update.Content == nil {
+		if h.AllowEmptyPatch {
+			return map[string]interface{}{}, nil
+		}
+		return nil, errors.New("No fields to update")
+	}
+
+	if update.Title != nil && strings.TrimSpace(*update.Title) == "" {
+		return nil, errors.New("Title cannot be empty")
+	}
+
 	updates := make(map[string]interface{})
 	if update.Title != nil {
 		updates["title"] = *update.Title
 	}
 	if update.Content != nil {
-		updates["content"] = *update.Content
+		updates["content"] = h.prepareContent(*update.Content)
+	}
+
+	return updates, nil
+}
+
+func patchFromQuery(q url.Values) UpdateNoteRequest {
+	var update UpdateNoteRequest
+	if q.Has("title") {
+		title := q.Get("title")
+		update.Title = &title
+	}
+	if q.Has("content") {
+		content := q.Get("content")
+		update.Content = &content
+	}
+	return update
+}
+
+// GetNoteChecksum возвращает SHA-256 хранимого содержимого заметки
+func (h *Handler) GetNoteChecksum(w http.ResponseWriter, r *http.Request) {
+	id, err := h.parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
+		return
 	}
 
-	err = h.Repo.UpdatePartial(id, updates)
+	note, err := h.Repo.GetByID(id)
 	if err != nil {
 		if err == repo.ErrNoteNotFound {
 			respondWithError(w, http.StatusNotFound, "Note not found")
 		} else {
-			respondWithError(w, http.StatusInternalServerError, "Failed to update note")
+			respondWithError(w, http.StatusInternalServerError, "Failed to get note")
 		}
 		return
 	}
 
-	updatedNote, err := h.Repo.GetByID(id)
+	respondWithJSON(w, http.StatusOK, ChecksumResponse{
+		Algorithm: "sha256",
+		Hex:       contentSHA256(note.Content),
+	})
+}
+
+// GetNoteSize возвращает объем, занимаемый заметкой, в байтах UTF-8
+func (h *Handler) GetNoteSize(w http.ResponseWriter, r *http.Request) {
+	id, err := h.parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
+		return
+	}
+
+	note, err := h.Repo.GetByID(id)
+	if err != nil {
+		if err == repo.ErrNoteNotFound {
+			respondWithError(w, http.StatusNotFound, "Note not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get note")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, noteSize(*note))
+}
+
+// GetStorage возвращает суммарный объем всех заметок
+func (h *Handler) GetStorage(w http.ResponseWriter, r *http.Request) {
+	notes, err := h.Repo.GetAll()
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve updated note")
+		respondWithError(w, http.StatusInternalServerError, "Failed to get notes")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, updatedNote)
+	resp := StorageResponse{Notes: len(notes)}
+	for _, note := range notes {
+		size := noteSize(note)
+		resp.TitleBytes += size.TitleBytes
+		resp.ContentBytes += size.ContentBytes
+		resp.TotalBytes += size.TotalBytes
+	}
+
+	respondWithJSON(w, http.StatusOK, resp)
 }
 
-// DeleteNote удаляет заметку
-func (h *Handler) DeleteNote(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseInt(idStr, 10, 64)
+// GetNoteAudit возвращает журнал действий над заметкой, в том числе удаленной
+func (h *Handler) GetNoteAudit(w http.ResponseWriter, r *http.Request) {
+	id, err := h.parseID(chi.URLParam(r, "id"))
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
 		return
 	}
 
-	err = h.Repo.Delete(id)
+	trail, err := h.Repo.AuditTrail(id)
 	if err != nil {
 		if err == repo.ErrNoteNotFound {
 			respondWithError(w, http.StatusNotFound, "Note not found")
 		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get audit trail")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, trail)
+}
+
+// DeleteNote удаляет заметку. ?dependents=ignore|block|mark определяет, что делать,
+// если заметку подключают другие через {{include:id}}
+func (h *Handler) DeleteNote(w http.ResponseWriter, r *http.Request) {
+	id, err := h.parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
+		return
+	}
+
+	policyName := r.URL.Query().Get("dependents")
+	if policyName == "" {
+		policyName = h.DeleteDependents
+	}
+
+	var policy repo.DependentsPolicy
+	switch policyName {
+	case "", config.DependentsIgnore:
+		policy = repo.DependentsIgnore
+	case config.DependentsBlock:
+		policy = repo.DependentsBlock
+	case config.DependentsMark:
+		policy = repo.DependentsMark
+	default:
+		respondWithError(w, http.StatusBadRequest, "Dependents must be one of: ignore, block, mark")
+		return
+	}
+
+	dependents, err := h.Repo.DeleteWithDependents(id, policy, h.refIDs())
+	if err != nil {
+		switch err {
+		case repo.ErrNoteNotFound:
+			respondWithError(w, http.StatusNotFound, "Note not found")
+		case repo.ErrNoteHasDependents:
+			ids := make([]interface{}, 0, len(dependents))
+			for _, depID := range dependents {
+				ids = append(ids, h.idOut(depID))
+			}
+			respondWithJSON(w, http.StatusConflict, DependentsErrorResponse{
+				Error:      "Note is included by other notes",
+				Dependents: ids,
+			})
+		default:
 			respondWithError(w, http.StatusInternalServerError, "Failed to delete note")
 		}
 		return
@@ -170,6 +953,95 @@ func (h *Handler) DeleteNote(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// contentSHA256 - hex SHA-256 содержимого без нормализации, в отличие от repo.ContentHash
+func contentSHA256(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func noteSize(n core.Note) NoteSizeResponse {
+	return NoteSizeResponse{
+		TitleBytes:   len(n.Title),
+		ContentBytes: len(n.Content),
+		TotalBytes:   len(n.Title) + len(n.Content),
+	}
+}
+
+// noteMarkdown оформляет заметку как Markdown-раздел: заголовок из названия и содержимое
+func noteMarkdown(n core.Note) string {
+	md := "# " + strings.TrimSpace(n.Title) + "\n\n"
+	if content := strings.TrimRight(n.Content, "\n"); content != "" {
+		md += content + "\n"
+	}
+	return md
+}
+
+// prepareContent применяет к содержимому заметки настроенные преобразования перед записью
+func (h *Handler) prepareContent(content string) string {
+	for _, t := range h.Transformers {
+		content = t.Transform(content)
+	}
+	return content
+}
+
+// sortNotes сортирует заметки по полю из параметра sort; по умолчанию - по дате создания
+func sortNotes(notes []core.Note, field string) error {
+	var less func(a, b core.Note) bool
+	switch field {
+	case "", "created_at":
+		less = func(a, b core.Note) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case "id":
+		less = func(a, b core.Note) bool { return a.ID < b.ID }
+	case "title":
+		less = func(a, b core.Note) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) }
+	default:
+		return errors.New("Sort must be one of: created_at, id, title")
+	}
+
+	sort.SliceStable(notes, func(i, j int) bool { return less(notes[i], notes[j]) })
+	return nil
+}
+
+// preferMaxItems достает max-items из заголовка Prefer (RFC 7240)
+func preferMaxItems(r *http.Request) (int, bool) {
+	for _, header := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(header, ",") {
+			name, value, found := strings.Cut(strings.TrimSpace(pref), "=")
+			if !found || !strings.EqualFold(strings.TrimSpace(name), "max-items") {
+				continue
+			}
+			n, err := strconv.Atoi(strings.Trim(strings.TrimSpace(value), `"`))
+			if err != nil || n < 0 {
+				return 0, false
+			}
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// parseTZOffset разбирает смещение вида "+03:00", "-0500" или "Z"; пустое значение - UTC.
+// Незакодированный "+" в query превращается в пробел, поэтому ведущий пробел читается как "+".
+func parseTZOffset(s string) (*time.Location, error) {
+	if s == "" || s == "Z" || strings.EqualFold(s, "UTC") {
+		return time.UTC, nil
+	}
+	if strings.HasPrefix(s, " ") {
+		s = "+" + s[1:]
+	}
+
+	t, err := time.Parse("-07:00", s)
+	if err != nil {
+		t, err = time.Parse("-0700", s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, offset := t.Zone()
+	return time.FixedZone(s, offset), nil
+}
+
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -184,3 +1056,43 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	encoder.SetIndent("", "  ")
 	encoder.Encode(payload)
 }
+
+// streamFlushEvery - через сколько элементов streamJSONArray сбрасывает ответ клиенту
+const streamFlushEvery = 100
+
+// streamJSONArray пишет массив из элементов, которые по одному отдает next, пока тот
+// не вернет false; весь ответ в памяти не собирается. Формат совпадает с respondWithJSON
+func streamJSONArray(w http.ResponseWriter, next func() (interface{}, bool)) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	item, ok := next()
+	if !ok {
+		io.WriteString(w, "[]\n")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	io.WriteString(w, "[\n")
+	for i := 0; ok; i++ {
+		data, err := json.MarshalIndent(item, "  ", "  ")
+		if err != nil {
+			// статус уже отправлен, поэтому ответ просто обрывается и клиент получит невалидный JSON
+			log.Printf("stream JSON array: item %d: %v", i, err)
+			return
+		}
+		io.WriteString(w, "  ")
+		w.Write(data)
+
+		// следующий элемент нужен заранее, чтобы знать, ставить ли запятую
+		item, ok = next()
+		if ok {
+			io.WriteString(w, ",")
+		}
+		io.WriteString(w, "\n")
+		if (i+1)%streamFlushEvery == 0 {
+			rc.Flush()
+		}
+	}
+	io.WriteString(w, "]\n")
+}
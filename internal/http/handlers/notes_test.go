@@ -0,0 +1,808 @@
+package handlers_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/notes-api/internal/config"
+	"example.com/notes-api/internal/core"
+	"example.com/notes-api/internal/http/handlers"
+	"example.com/notes-api/internal/repo"
+)
+
+func TestGetTimelineDayBuckets(t *testing.T) {
+	api := newTestAPI(t, nil)
+	for i, created := range []string{
+		"2024-03-01T10:00:00Z",
+		"2024-03-01T22:30:00Z", // 2024-03-02 01:30 по +03:00
+		"2024-03-02T09:00:00Z",
+	} {
+		n := core.Note{ID: int64(i + 1), Title: "note", CreatedAt: mustTime(t, created)}
+		if _, err := api.handler.Repo.Restore(n, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// "+" без кодирования приходит в query пробелом
+	rec := api.do(http.MethodGet, "/api/v1/notes/timeline?granularity=day&tz=+03:00", "")
+	expectStatus(t, rec, http.StatusOK)
+
+	var groups []struct {
+		Period string
+		Notes  []struct{ ID float64 }
+	}
+	decodeJSON(t, rec, &groups)
+
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2: %+v", len(groups), groups)
+	}
+	if groups[0].Period != "2024-03-02" || len(groups[0].Notes) != 2 {
+		t.Errorf("first group = %+v, want 2024-03-02 with 2 notes", groups[0])
+	}
+	if groups[0].Notes[0].ID != 3 || groups[0].Notes[1].ID != 2 {
+		t.Errorf("notes in group are not newest first: %+v", groups[0].Notes)
+	}
+	if groups[1].Period != "2024-03-01" || len(groups[1].Notes) != 1 {
+		t.Errorf("second group = %+v, want 2024-03-01 with 1 note", groups[1])
+	}
+}
+
+func TestGetTimelineRejectsUnknownGranularity(t *testing.T) {
+	api := newTestAPI(t, nil)
+
+	// ответ не должен зависеть от того, пусто ли хранилище
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/timeline?granularity=year", ""), http.StatusBadRequest)
+	api.createNote("a", "")
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/timeline?granularity=year", ""), http.StatusBadRequest)
+}
+
+func TestGetAllNotesPreferMaxItems(t *testing.T) {
+	api := newTestAPI(t, nil)
+	for _, title := range []string{"a", "b", "c"} {
+		api.createNote(title, "")
+	}
+
+	rec := api.do(http.MethodGet, "/api/v1/notes", "", "Prefer", "return=minimal, max-items=2")
+	expectStatus(t, rec, http.StatusOK)
+
+	var notes []struct{ Title string }
+	decodeJSON(t, rec, &notes)
+	if len(notes) != 2 || notes[0].Title != "a" || notes[1].Title != "b" {
+		t.Fatalf("got %+v, want the first two notes", notes)
+	}
+	if got := rec.Header().Get("Preference-Applied"); got != "max-items=2" {
+		t.Fatalf("Preference-Applied = %q, want max-items=2", got)
+	}
+
+	// предпочтение больше числа заметок ничего не режет и не подтверждается
+	rec = api.do(http.MethodGet, "/api/v1/notes", "", "Prefer", "max-items=10")
+	decodeJSON(t, rec, &notes)
+	if len(notes) != 3 || rec.Header().Get("Preference-Applied") != "" {
+		t.Fatalf("max-items=10: got %d notes, Preference-Applied %q", len(notes), rec.Header().Get("Preference-Applied"))
+	}
+}
+
+func TestGetCombinedMarkdown(t *testing.T) {
+	api := newTestAPI(t, nil)
+	api.createNote("Shopping", "milk\neggs")
+	api.createNote("  Ideas ", "")
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/combined.md", "")
+	expectStatus(t, rec, http.StatusOK)
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/markdown") {
+		t.Fatalf("Content-Type = %q, want text/markdown", ct)
+	}
+
+	want := "# Shopping\n\nmilk\neggs\n\n---\n\n# Ideas\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("body = %q, want %q", got, want)
+	}
+}
+
+func TestGetNotesByHash(t *testing.T) {
+	api := newTestAPI(t, nil)
+	first := api.createNote("a", "same text")
+	second := api.createNote("b", "same text\r\n")
+	api.createNote("c", "other text")
+
+	hash := repo.ContentHash("same text")
+	rec := api.do(http.MethodGet, "/api/v1/notes/by-hash/"+strings.ToUpper(hash), "")
+	expectStatus(t, rec, http.StatusOK)
+
+	var notes []struct{ ID interface{} }
+	decodeJSON(t, rec, &notes)
+	if len(notes) != 2 || idString(notes[0].ID) != first || idString(notes[1].ID) != second {
+		t.Fatalf("got %+v, want notes %s and %s", notes, first, second)
+	}
+
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/by-hash/abc", ""), http.StatusBadRequest)
+}
+
+type streamResult struct {
+	Line       int
+	Status     string
+	ID         interface{}
+	Error      string
+	RetryAfter int `json:"retry_after"`
+}
+
+func decodeNDJSON(t *testing.T, body io.Reader) []streamResult {
+	t.Helper()
+	var results []streamResult
+	decoder := json.NewDecoder(body)
+	for {
+		var r streamResult
+		if err := decoder.Decode(&r); err == io.EOF {
+			return results
+		} else if err != nil {
+			t.Fatalf("decode result %d: %v", len(results)+1, err)
+		}
+		results = append(results, r)
+	}
+}
+
+func TestStreamImport(t *testing.T) {
+	api := newTestAPI(t, nil)
+
+	body := `{"Title":"first","Content":"one"}
+not json
+{"Content":"no title"}
+
+{"Title":"last","Content":"no trailing newline"}`
+	rec := api.do(http.MethodPost, "/api/v1/notes/stream-import", body)
+	expectStatus(t, rec, http.StatusOK)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	results := decodeNDJSON(t, rec.Body)
+	want := []streamResult{
+		{Line: 1, Status: "created"},
+		{Line: 2, Status: "error", Error: "Invalid JSON"},
+		{Line: 3, Status: "error", Error: "Title is required"},
+		{Line: 5, Status: "created"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, w := range want {
+		got := results[i]
+		if got.Line != w.Line || got.Status != w.Status || got.Error != w.Error {
+			t.Errorf("result %d = %+v, want %+v", i, got, w)
+		}
+		if w.Status == "created" && got.ID == nil {
+			t.Errorf("result %d has no ID", i)
+		}
+	}
+
+	notes, _ := api.handler.Repo.GetAll()
+	if len(notes) != 2 || notes[0].Title != "first" || notes[1].Title != "last" {
+		t.Fatalf("stored notes = %+v, want first and last", notes)
+	}
+}
+
+func TestStreamImportStrictStopsOnFirstError(t *testing.T) {
+	api := newTestAPI(t, nil)
+
+	rec := api.do(http.MethodPost, "/api/v1/notes/stream-import?strict=true", "{\"Title\":\"a\"}\n{}\n{\"Title\":\"b\"}\n")
+	results := decodeNDJSON(t, rec.Body)
+	if len(results) != 2 || results[1].Status != "error" {
+		t.Fatalf("results = %+v, want import to stop after the error on line 2", results)
+	}
+	if notes, _ := api.handler.Repo.GetAll(); len(notes) != 1 {
+		t.Fatalf("stored %d notes, want 1", len(notes))
+	}
+}
+
+func TestStreamImportOversizedLineDoesNotAbort(t *testing.T) {
+	api := newTestAPI(t, nil)
+
+	huge := `{"Title":"huge","Content":"` + strings.Repeat("x", 2<<20) + `"}`
+	body := "{\"Title\":\"before\"}\n" + huge + "\n{\"Title\":\"after\"}\n"
+	results := decodeNDJSON(t, api.do(http.MethodPost, "/api/v1/notes/stream-import", body).Body)
+
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3: %+v", len(results), results)
+	}
+	if results[1].Line != 2 || results[1].Status != "error" {
+		t.Errorf("oversized line result = %+v, want an error for line 2", results[1])
+	}
+	if results[2].Line != 3 || results[2].Status != "created" {
+		t.Errorf("line after the oversized one = %+v, want created", results[2])
+	}
+}
+
+func TestStreamImportExpectContinue(t *testing.T) {
+	api := newTestAPI(t, nil)
+	srv := httptest.NewServer(api.router)
+	defer srv.Close()
+
+	body := "{\"Title\":\"a\"}\n{\"Title\":\"b\"}\n"
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/notes/stream-import", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// curl сам добавляет этот заголовок для тел больше 1 МБ
+	req.Header.Set("Expect", "100-continue")
+
+	client := &http.Client{Transport: &http.Transport{ExpectContinueTimeout: 5 * time.Second}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	results := decodeNDJSON(t, resp.Body)
+	if len(results) != 2 || results[0].Status != "created" || results[1].Status != "created" {
+		t.Fatalf("results = %+v, want two created notes", results)
+	}
+}
+
+func TestGetOldestNotes(t *testing.T) {
+	api := newTestAPI(t, nil)
+	first := api.createNote("a", "")
+	api.createNote("b", "")
+	api.createNote("c", "")
+	expectStatus(t, api.do(http.MethodPatch, "/api/v1/notes/"+first, `{"content":"edited"}`), http.StatusOK)
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/oldest?limit=2", "")
+	expectStatus(t, rec, http.StatusOK)
+
+	var notes []struct{ Title string }
+	decodeJSON(t, rec, &notes)
+	if len(notes) != 2 || notes[0].Title != "b" || notes[1].Title != "c" {
+		t.Fatalf("got %+v, want b then c: the edited note is no longer the oldest", notes)
+	}
+
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/oldest?limit=0", ""), http.StatusBadRequest)
+}
+
+func TestCreateNoteTrimsTrailingWhitespace(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		api := newTestAPI(t, func(h *handlers.Handler, cfg *config.Config) {
+			if enabled {
+				h.Transformers = append(h.Transformers, core.TrimTrailingWhitespace)
+			}
+		})
+
+		id := api.createNote("t", "keep  \nthis\t")
+		rec := api.do(http.MethodGet, "/api/v1/notes/"+id, "")
+
+		var note struct{ Content string }
+		decodeJSON(t, rec, &note)
+		want := "keep  \nthis\t"
+		if enabled {
+			want = "keep\nthis"
+		}
+		if note.Content != want {
+			t.Errorf("trimming %v: content = %q, want %q", enabled, note.Content, want)
+		}
+	}
+}
+
+func TestNoteSizeCountsUTF8Bytes(t *testing.T) {
+	api := newTestAPI(t, nil)
+	// 6 кириллических букв по 2 байта и эмодзи на 4 байта
+	id := api.createNote("Tи", "Привет🙂")
+	api.createNote("x", "abc")
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/"+id+"/size", "")
+	expectStatus(t, rec, http.StatusOK)
+
+	var size struct {
+		TitleBytes   int `json:"title_bytes"`
+		ContentBytes int `json:"content_bytes"`
+		TotalBytes   int `json:"total_bytes"`
+	}
+	decodeJSON(t, rec, &size)
+	if size.TitleBytes != 3 || size.ContentBytes != 16 || size.TotalBytes != 19 {
+		t.Fatalf("size = %+v, want 3 + 16 = 19 bytes", size)
+	}
+
+	var storage struct {
+		Notes      int `json:"notes"`
+		TotalBytes int `json:"total_bytes"`
+	}
+	decodeJSON(t, api.do(http.MethodGet, "/api/v1/notes/storage", ""), &storage)
+	if storage.Notes != 2 || storage.TotalBytes != 19+4 {
+		t.Fatalf("storage = %+v, want 2 notes and 23 bytes", storage)
+	}
+}
+
+func TestPreviewPatchDoesNotSave(t *testing.T) {
+	api := newTestAPI(t, nil)
+	id := api.createNote("Original", "body")
+
+	rec := api.do(http.MethodPost, "/api/v1/notes/"+id+"/patch-preview", `{"title":"Changed"}`)
+	expectStatus(t, rec, http.StatusOK)
+
+	var preview struct {
+		Title     string
+		Content   string
+		UpdatedAt *time.Time
+	}
+	decodeJSON(t, rec, &preview)
+	if preview.Title != "Changed" || preview.Content != "body" || preview.UpdatedAt == nil {
+		t.Fatalf("preview = %+v, want the changed title with UpdatedAt set", preview)
+	}
+
+	var stored struct {
+		Title     string
+		UpdatedAt *time.Time
+	}
+	decodeJSON(t, api.do(http.MethodGet, "/api/v1/notes/"+id, ""), &stored)
+	if stored.Title != "Original" || stored.UpdatedAt != nil {
+		t.Fatalf("stored note = %+v, want it untouched", stored)
+	}
+
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/notes/"+id+"/patch-preview", `{"title":""}`), http.StatusBadRequest)
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/notes/999/patch-preview", `{"title":"x"}`), http.StatusNotFound)
+}
+
+func TestRepairLinks(t *testing.T) {
+	tests := []struct {
+		name, query, want string
+	}{
+		{"report only", "", "see [[1]] and [[2]]"},
+		{"strip", "?apply=true", "see  and [[2]]"},
+		{"mark", "?apply=true&mode=mark", "see [[deleted:1]] and [[2]]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t, nil)
+			deleted := api.createNote("gone", "")
+			api.createNote("kept", "")
+			linking := api.createNote("links", "see [[1]] and [[2]]")
+			expectStatus(t, api.do(http.MethodDelete, "/api/v1/notes/"+deleted, ""), http.StatusOK)
+
+			rec := api.do(http.MethodPost, "/api/v1/notes/repair-links"+tt.query, "")
+			expectStatus(t, rec, http.StatusOK)
+
+			var resp struct {
+				Applied      bool `json:"applied"`
+				NotesTouched int  `json:"notes_touched"`
+				Notes        []struct {
+					NoteID  interface{} `json:"note_id"`
+					Missing []int64     `json:"missing"`
+				} `json:"notes"`
+			}
+			decodeJSON(t, rec, &resp)
+			if resp.NotesTouched != 1 || len(resp.Notes) != 1 {
+				t.Fatalf("response = %s, want one note with dangling links", rec.Body)
+			}
+			if idString(resp.Notes[0].NoteID) != linking || len(resp.Notes[0].Missing) != 1 || resp.Notes[0].Missing[0] != 1 {
+				t.Fatalf("dangling = %+v, want note %s missing [1]", resp.Notes[0], linking)
+			}
+
+			var note struct{ Content string }
+			decodeJSON(t, api.do(http.MethodGet, "/api/v1/notes/"+linking, ""), &note)
+			if note.Content != tt.want {
+				t.Fatalf("content = %q, want %q", note.Content, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetNotesWithLinks(t *testing.T) {
+	api := newTestAPI(t, nil)
+	api.createNote("plain", "no links here")
+	api.createNote("links", "Read https://go.dev/doc and http://example.com/page.")
+
+	var all []struct {
+		Note struct{ Title string } `json:"note"`
+		URLs []string               `json:"urls"`
+	}
+	decodeJSON(t, api.do(http.MethodGet, "/api/v1/notes/with-links", ""), &all)
+	if len(all) != 1 || all[0].Note.Title != "links" || len(all[0].URLs) != 2 ||
+		all[0].URLs[0] != "https://go.dev/doc" || all[0].URLs[1] != "http://example.com/page" {
+		t.Fatalf("with-links = %+v, want both URLs of the links note", all)
+	}
+
+	decodeJSON(t, api.do(http.MethodGet, "/api/v1/notes/with-links?domain=example.com", ""), &all)
+	if len(all) != 1 || len(all[0].URLs) != 1 || all[0].URLs[0] != "http://example.com/page" {
+		t.Fatalf("with-links?domain = %+v, want only the example.com URL", all)
+	}
+}
+
+func TestGetNoteAudit(t *testing.T) {
+	api := newTestAPI(t, nil)
+	id := api.createNote("a", "")
+	api.do(http.MethodPatch, "/api/v1/notes/"+id, `{"title":"b"}`)
+	api.do(http.MethodDelete, "/api/v1/notes/"+id, "")
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/"+id+"/audit", "")
+	expectStatus(t, rec, http.StatusOK)
+
+	var trail []struct {
+		Action string
+		Fields []string
+	}
+	decodeJSON(t, rec, &trail)
+	if len(trail) != 3 || trail[0].Action != "created" || trail[1].Action != "updated" ||
+		len(trail[1].Fields) != 1 || trail[1].Fields[0] != "title" || trail[2].Action != "deleted" {
+		t.Fatalf("trail = %+v, want created, updated [title], deleted", trail)
+	}
+
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/999/audit", ""), http.StatusNotFound)
+}
+
+func TestPatchNoteFromQuery(t *testing.T) {
+	api := newTestAPI(t, nil)
+	id := api.createNote("Old", "body")
+
+	rec := api.do(http.MethodPatch, "/api/v1/notes/"+id+"?title=New%20title", "")
+	expectStatus(t, rec, http.StatusOK)
+
+	var note struct{ Title, Content string }
+	decodeJSON(t, api.do(http.MethodGet, "/api/v1/notes/"+id, ""), &note)
+	if note.Title != "New title" || note.Content != "body" {
+		t.Fatalf("note = %+v, want the title from the query and content untouched", note)
+	}
+
+	// при непустом теле query-параметры не используются
+	api.do(http.MethodPatch, "/api/v1/notes/"+id+"?title=Ignored", `{"content":"from body"}`)
+	decodeJSON(t, api.do(http.MethodGet, "/api/v1/notes/"+id, ""), &note)
+	if note.Title != "New title" || note.Content != "from body" {
+		t.Fatalf("note = %+v, want only the body applied", note)
+	}
+
+	expectStatus(t, api.do(http.MethodPatch, "/api/v1/notes/"+id+"?title=", ""), http.StatusBadRequest)
+}
+
+func TestGetNoteExpandsIncludes(t *testing.T) {
+	api := newTestAPI(t, nil)
+	part := api.createNote("part", "shared text")
+	id := api.createNote("whole", "before\n{{include:"+part+"}}\nafter")
+
+	var note struct{ Content string }
+	decodeJSON(t, api.do(http.MethodGet, "/api/v1/notes/"+id+"?expand=true", ""), &note)
+	if note.Content != "before\nshared text\nafter" {
+		t.Fatalf("expanded content = %q", note.Content)
+	}
+
+	decodeJSON(t, api.do(http.MethodGet, "/api/v1/notes/"+id, ""), &note)
+	if note.Content != "before\n{{include:"+part+"}}\nafter" {
+		t.Fatalf("content without expand = %q, want the macro kept", note.Content)
+	}
+}
+
+func TestEmptyPatch(t *testing.T) {
+	strict := newTestAPI(t, nil)
+	id := strict.createNote("title", "")
+	expectStatus(t, strict.do(http.MethodPatch, "/api/v1/notes/"+id, `{}`), http.StatusBadRequest)
+
+	lenient := newTestAPI(t, func(h *handlers.Handler, cfg *config.Config) {
+		h.AllowEmptyPatch = true
+	})
+	id = lenient.createNote("title", "")
+	rec := lenient.do(http.MethodPatch, "/api/v1/notes/"+id, `{}`)
+	expectStatus(t, rec, http.StatusOK)
+
+	var note struct {
+		Title     string
+		UpdatedAt *time.Time
+	}
+	decodeJSON(t, rec, &note)
+	if note.Title != "title" || note.UpdatedAt != nil {
+		t.Fatalf("note = %+v, want it unchanged, including UpdatedAt", note)
+	}
+
+	expectStatus(t, lenient.do(http.MethodPatch, "/api/v1/notes/999", `{}`), http.StatusNotFound)
+}
+
+func TestGetDelta(t *testing.T) {
+	api := newTestAPI(t, nil)
+	unchanged := api.createNote("a", "")
+	updated := api.createNote("b", "")
+	deleted := api.createNote("c", "")
+
+	since := time.Now()
+	expectStatus(t, api.do(http.MethodPatch, "/api/v1/notes/"+updated, `{"content":"new"}`), http.StatusOK)
+	expectStatus(t, api.do(http.MethodDelete, "/api/v1/notes/"+deleted, ""), http.StatusOK)
+
+	seen := map[string]time.Time{unchanged: since, updated: since, deleted: since, "999": since}
+	body, _ := json.Marshal(seen)
+	rec := api.do(http.MethodPost, "/api/v1/notes/delta", string(body))
+	expectStatus(t, rec, http.StatusOK)
+
+	var result map[string]struct {
+		Status string `json:"status"`
+		Note   *struct {
+			Content string
+		} `json:"note"`
+	}
+	decodeJSON(t, rec, &result)
+
+	want := map[string]string{unchanged: "unchanged", updated: "updated", deleted: "deleted", "999": "not_found"}
+	for id, status := range want {
+		if result[id].Status != status {
+			t.Errorf("note %s: status %q, want %q", id, result[id].Status, status)
+		}
+	}
+	if n := result[updated].Note; n == nil || n.Content != "new" {
+		t.Errorf("updated entry carries note %+v, want the new content", n)
+	}
+	if result[unchanged].Note != nil || result[deleted].Note != nil {
+		t.Error("only updated entries should carry the note")
+	}
+}
+
+func TestCustomContentTransformer(t *testing.T) {
+	api := newTestAPI(t, func(h *handlers.Handler, cfg *config.Config) {
+		h.Transformers = []core.ContentTransformer{
+			core.TransformFunc(strings.ToUpper),
+			core.TrimTrailingWhitespace,
+		}
+	})
+
+	id := api.createNote("title", "hello  ")
+	var note struct{ Title, Content string }
+	decodeJSON(t, api.do(http.MethodGet, "/api/v1/notes/"+id, ""), &note)
+	if note.Content != "HELLO" || note.Title != "title" {
+		t.Fatalf("created note = %+v, want only the content transformed", note)
+	}
+
+	api.do(http.MethodPatch, "/api/v1/notes/"+id, `{"content":"patched"}`)
+	decodeJSON(t, api.do(http.MethodGet, "/api/v1/notes/"+id, ""), &note)
+	if note.Content != "PATCHED" {
+		t.Fatalf("patched content = %q, want PATCHED", note.Content)
+	}
+}
+
+func TestListWithoutContent(t *testing.T) {
+	api := newTestAPI(t, nil)
+	api.createNote("title", "large body")
+
+	rec := api.do(http.MethodGet, "/api/v1/notes?content=false", "")
+	expectStatus(t, rec, http.StatusOK)
+
+	var list []map[string]interface{}
+	decodeJSON(t, rec, &list)
+	if len(list) != 1 {
+		t.Fatalf("got %d notes, want 1", len(list))
+	}
+	if content, ok := list[0]["Content"]; !ok || content != nil {
+		t.Fatalf("Content = %v, want null", content)
+	}
+	url, _ := list[0]["ContentURL"].(string)
+	if url != "/api/v1/notes/1" {
+		t.Fatalf("ContentURL = %q", url)
+	}
+
+	var note struct{ Content string }
+	decodeJSON(t, api.do(http.MethodGet, url, ""), &note)
+	if note.Content != "large body" {
+		t.Fatalf("content at %s = %q", url, note.Content)
+	}
+
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes?content=maybe", ""), http.StatusBadRequest)
+}
+
+func TestNoteChecksumMatchesHeader(t *testing.T) {
+	api := newTestAPI(t, nil)
+	id := api.createNote("t", "Привет, мир")
+
+	sum := sha256.Sum256([]byte("Привет, мир"))
+	want := hex.EncodeToString(sum[:])
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/"+id+"/checksum", "")
+	expectStatus(t, rec, http.StatusOK)
+	var checksum struct {
+		Algorithm string `json:"algorithm"`
+		Hex       string `json:"hex"`
+	}
+	decodeJSON(t, rec, &checksum)
+	if checksum.Algorithm != "sha256" || checksum.Hex != want {
+		t.Fatalf("checksum = %+v, want sha256 %s", checksum, want)
+	}
+
+	rec = api.do(http.MethodGet, "/api/v1/notes/"+id, "")
+	expectStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("X-Content-SHA256"); got != want {
+		t.Fatalf("X-Content-SHA256 = %q, want %q", got, want)
+	}
+
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/999/checksum", ""), http.StatusNotFound)
+}
+
+func TestGetUntouchedNotes(t *testing.T) {
+	api := newTestAPI(t, nil)
+	edited := api.createNote("edited", "one")
+	untouched := api.createNote("untouched", "two")
+	expectStatus(t, api.do(http.MethodPatch, "/api/v1/notes/"+edited, `{"content":"three"}`), http.StatusOK)
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/untouched", "")
+	expectStatus(t, rec, http.StatusOK)
+	var notes []exportedNote
+	decodeJSON(t, rec, &notes)
+	if len(notes) != 1 || idString(notes[0].ID) != untouched || notes[0].UpdatedAt != nil {
+		t.Fatalf("untouched = %+v, want only note %s", notes, untouched)
+	}
+}
+
+func TestDeleteNoteDependents(t *testing.T) {
+	api := newTestAPI(t, nil)
+	part := api.createNote("part", "shared")
+	whole := api.createNote("whole", "see {{include:"+part+"}}")
+
+	rec := api.do(http.MethodDelete, "/api/v1/notes/"+part+"?dependents=block", "")
+	expectStatus(t, rec, http.StatusConflict)
+	var conflict struct {
+		Error      string        `json:"error"`
+		Dependents []interface{} `json:"dependents"`
+	}
+	decodeJSON(t, rec, &conflict)
+	if len(conflict.Dependents) != 1 || idString(conflict.Dependents[0]) != whole {
+		t.Fatalf("dependents = %v, want [%s]", conflict.Dependents, whole)
+	}
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/"+part, ""), http.StatusOK)
+
+	expectStatus(t, api.do(http.MethodDelete, "/api/v1/notes/"+part+"?dependents=mark", ""), http.StatusOK)
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes/"+part, ""), http.StatusNotFound)
+	if got := getNote(t, api, whole).Content; got != "see [include:"+part+" deleted]" {
+		t.Fatalf("dependent content = %q, want the macro marked deleted", got)
+	}
+
+	expectStatus(t, api.do(http.MethodDelete, "/api/v1/notes/"+whole+"?dependents=drop", ""), http.StatusBadRequest)
+}
+
+func TestGetTOC(t *testing.T) {
+	api := newTestAPI(t, nil)
+	id := api.createNote("guide", "# Intro\ntext\n```\n# not a heading\n```\n## Usage ##")
+	api.createNote("plain", "no headings")
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/toc", "")
+	expectStatus(t, rec, http.StatusOK)
+	var toc []struct {
+		ID       interface{}    `json:"id"`
+		Title    string         `json:"title"`
+		Headings []core.Heading `json:"headings"`
+	}
+	decodeJSON(t, rec, &toc)
+	if len(toc) != 2 {
+		t.Fatalf("toc = %+v, want 2 entries", toc)
+	}
+
+	entry := toc[0]
+	want := []core.Heading{{Level: 1, Text: "Intro", Line: 1}, {Level: 2, Text: "Usage", Line: 6}}
+	if idString(entry.ID) != id || entry.Title != "guide" || len(entry.Headings) != len(want) {
+		t.Fatalf("entry = %+v, want note %s with %v", entry, id, want)
+	}
+	for i := range want {
+		if entry.Headings[i] != want[i] {
+			t.Errorf("heading %d = %+v, want %+v", i, entry.Headings[i], want[i])
+		}
+	}
+	if toc[1].Headings == nil || len(toc[1].Headings) != 0 {
+		t.Fatalf("note without headings = %+v, want an empty list", toc[1])
+	}
+}
+
+func TestLintNotes(t *testing.T) {
+	api := newTestAPI(t, nil)
+	api.createNote("clean", "# ok\ntext")
+	broken := api.createNote("broken", "intro\n```\ncode")
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/lint", "")
+	expectStatus(t, rec, http.StatusOK)
+	var results []struct {
+		ID     interface{}      `json:"id"`
+		Issues []core.LintIssue `json:"issues"`
+	}
+	decodeJSON(t, rec, &results)
+	if len(results) != 1 || idString(results[0].ID) != broken {
+		t.Fatalf("lint = %+v, want only note %s", results, broken)
+	}
+	want := core.LintIssue{Line: 2, Message: "code fence is never closed"}
+	if issues := results[0].Issues; len(issues) != 1 || issues[0] != want {
+		t.Fatalf("issues = %+v, want %+v", issues, want)
+	}
+}
+
+func TestGetAllNotesStreamsLargeListing(t *testing.T) {
+	api := newTestAPI(t, nil)
+	rec := api.do(http.MethodGet, "/api/v1/notes", "")
+	expectStatus(t, rec, http.StatusOK)
+	if body := rec.Body.String(); body != "[]\n" {
+		t.Fatalf("empty listing = %q, want %q", body, "[]\n")
+	}
+
+	const total = 1000
+	for i := 1; i <= total; i++ {
+		if _, err := api.handler.Repo.Create(core.Note{Title: "n", Content: strings.Repeat("x", i%50)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec = api.do(http.MethodGet, "/api/v1/notes", "")
+	expectStatus(t, rec, http.StatusOK)
+
+	decoder := json.NewDecoder(rec.Body)
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('[') {
+		t.Fatalf("first token = %v, %v; want [", tok, err)
+	}
+	count := 0
+	for decoder.More() {
+		var n exportedNote
+		if err := decoder.Decode(&n); err != nil {
+			t.Fatalf("decode item %d: %v", count, err)
+		}
+		count++
+		if idString(n.ID) != strconv.Itoa(count) || len(n.Content) != count%50 {
+			t.Fatalf("item %d = %+v, want note %d", count, n, count)
+		}
+	}
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim(']') {
+		t.Fatalf("last token = %v, %v; want ]", tok, err)
+	}
+	if count != total {
+		t.Fatalf("decoded %d notes, want %d", count, total)
+	}
+}
+
+func TestExplainSearch(t *testing.T) {
+	api := newTestAPI(t, nil)
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/search/explain?q="+url.QueryEscape(`tag:work "exact phrase" foo`), "")
+	expectStatus(t, rec, http.StatusOK)
+	var query core.Query
+	decodeJSON(t, rec, &query)
+	if len(query.Terms) != 1 || query.Terms[0] != "foo" || len(query.Phrases) != 1 || query.Phrases[0] != "exact phrase" ||
+		len(query.Fields) != 1 || query.Fields[0] != (core.FieldFilter{Field: "tag", Value: "work"}) {
+		t.Fatalf("query = %+v", query)
+	}
+
+	rec = api.do(http.MethodGet, "/api/v1/notes/search/explain?q="+url.QueryEscape(`foo "bar`), "")
+	expectStatus(t, rec, http.StatusBadRequest)
+	var qerr struct {
+		Error    string `json:"error"`
+		Position int    `json:"position"`
+	}
+	decodeJSON(t, rec, &qerr)
+	if qerr.Position != 4 || qerr.Error != "unterminated quote at position 4" {
+		t.Fatalf("error = %+v, want unterminated quote at position 4", qerr)
+	}
+}
+
+func TestGetAllNotesPreferMaxItemsCountsFilteredNotes(t *testing.T) {
+	api := newTestAPI(t, nil)
+	seedWordCounts(api)
+
+	rec := api.do(http.MethodGet, "/api/v1/notes?min_words=3", "", "Prefer", "max-items=3")
+	var notes []struct{ Title string }
+	decodeJSON(t, rec, &notes)
+	if len(notes) != 3 || rec.Header().Get("Preference-Applied") != "" {
+		t.Fatalf("3 matching notes, max-items=3: got %d, Preference-Applied %q", len(notes), rec.Header().Get("Preference-Applied"))
+	}
+
+	rec = api.do(http.MethodGet, "/api/v1/notes?min_words=3", "", "Prefer", "max-items=2")
+	decodeJSON(t, rec, &notes)
+	if len(notes) != 2 || notes[0].Title != "www" || rec.Header().Get("Preference-Applied") != "max-items=2" {
+		t.Fatalf("max-items=2: got %+v, Preference-Applied %q", notes, rec.Header().Get("Preference-Applied"))
+	}
+}
+
+func TestGetDeltaReportsFirstInvalidIDInOrder(t *testing.T) {
+	api := newTestAPI(t, nil)
+	api.createNote("a", "")
+
+	body := `{"1":"2024-01-01T00:00:00Z","zz":"2024-01-01T00:00:00Z","bad":"2024-01-01T00:00:00Z"}`
+	for i := 0; i < 20; i++ {
+		rec := api.do(http.MethodPost, "/api/v1/notes/delta", body)
+		expectStatus(t, rec, http.StatusBadRequest)
+		var resp struct{ Error string }
+		decodeJSON(t, rec, &resp)
+		if resp.Error != "Invalid note ID: bad" {
+			t.Fatalf("attempt %d: error = %q, want the first invalid key in sorted order", i, resp.Error)
+		}
+	}
+}
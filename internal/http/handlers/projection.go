@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"example.com/notes-api/internal/core"
+)
+
+// projection описывает выражение ?projection=id,title,content[0:100]:
+// список полей, строковые поля можно обрезать срезом [start:end] по символам
+type projection []projectedField
+
+type projectedField struct {
+	field      noteField
+	start, end int
+	sliced     bool
+}
+
+type noteField struct {
+	key    string
+	isText bool
+	get    func(n core.Note) interface{}
+}
+
+// noteFields - поля заметки, доступные для проекции; ключи в ответе совпадают с полной заметкой
+var noteFields = map[string]noteField{
+	"id":         {key: "ID", get: func(n core.Note) interface{} { return n.ID }},
+	"title":      {key: "Title", isText: true, get: func(n core.Note) interface{} { return n.Title }},
+	"content":    {key: "Content", isText: true, get: func(n core.Note) interface{} { return n.Content }},
+	"created_at": {key: "CreatedAt", get: func(n core.Note) interface{} { return n.CreatedAt }},
+	"updated_at": {key: "UpdatedAt", get: func(n core.Note) interface{} { return n.UpdatedAt }},
+}
+
+// parseProjection разбирает выражение проекции; ошибка содержит позицию в строке
+func parseProjection(expr string) (projection, error) {
+	var p projection
+	pos := 0
+	for _, part := range strings.Split(expr, ",") {
+		f, err := parseProjectedField(part, pos)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, f)
+		pos += len(part) + 1
+	}
+	return p, nil
+}
+
+func parseProjectedField(part string, pos int) (projectedField, error) {
+	name := part
+	var slice string
+	i := strings.IndexByte(part, '[')
+	if i >= 0 {
+		if !strings.HasSuffix(part, "]") {
+			return projectedField{}, fmt.Errorf("projection: missing ']' at position %d", pos+len(part))
+		}
+		name, slice = part[:i], part[i+1:len(part)-1]
+	}
+
+	name = strings.TrimSpace(name)
+	field, ok := noteFields[strings.ToLower(name)]
+	if !ok {
+		return projectedField{}, fmt.Errorf("projection: unknown field %q at position %d", name, pos)
+	}
+
+	f := projectedField{field: field, end: -1}
+	if i < 0 {
+		return f, nil
+	}
+	if !field.isText {
+		return projectedField{}, fmt.Errorf("projection: field %q cannot be sliced at position %d", name, pos)
+	}
+
+	startStr, endStr, found := strings.Cut(slice, ":")
+	if !found {
+		return projectedField{}, fmt.Errorf("projection: slice must be [start:end] at position %d", pos)
+	}
+
+	var err error
+	if startStr != "" {
+		if f.start, err = strconv.Atoi(startStr); err != nil || f.start < 0 {
+			return projectedField{}, fmt.Errorf("projection: invalid slice start %q at position %d", startStr, pos)
+		}
+	}
+	if endStr != "" {
+		if f.end, err = strconv.Atoi(endStr); err != nil || f.end < f.start {
+			return projectedField{}, fmt.Errorf("projection: invalid slice end %q at position %d", endStr, pos)
+		}
+	}
+	f.sliced = true
+
+	return f, nil
+}
+
+func (p projection) apply(n core.Note) map[string]interface{} {
+	out := make(map[string]interface{}, len(p))
+	for _, f := range p {
+		value := f.field.get(n)
+		if f.sliced {
+			value = sliceRunes(value.(string), f.start, f.end)
+		}
+		out[f.field.key] = value
+	}
+	return out
+}
+
+// project применяет проекцию, отдавая ID в том же виде, что и полная заметка
+func (h *Handler) project(p projection, n core.Note) map[string]interface{} {
+	out := p.apply(n)
+	if _, ok := out["ID"]; ok {
+		out["ID"] = h.idOut(n.ID)
+	}
+	return out
+}
+
+// sliceRunes режет строку по символам, а не по байтам; end < 0 - до конца строки
+func sliceRunes(s string, start, end int) string {
+	runes := []rune(s)
+	if start > len(runes) {
+		start = len(runes)
+	}
+	if end < 0 || end > len(runes) {
+		end = len(runes)
+	}
+	return string(runes[start:end])
+}
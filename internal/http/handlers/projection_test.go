@@ -0,0 +1,43 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetNoteProjection(t *testing.T) {
+	api := newTestAPI(t, nil)
+	id := api.createNote("Title", "Привет, мир! Длинный текст")
+
+	rec := api.do(http.MethodGet, "/api/v1/notes/"+id+"?projection=id,content[0:6]", "")
+	expectStatus(t, rec, http.StatusOK)
+
+	var got map[string]interface{}
+	decodeJSON(t, rec, &got)
+	if len(got) != 2 {
+		t.Fatalf("got fields %v, want only ID and Content", got)
+	}
+	if idString(got["ID"]) != id {
+		t.Errorf("ID = %v, want %s", got["ID"], id)
+	}
+	// срез по символам, а не по байтам
+	if got["Content"] != "Привет" {
+		t.Errorf("Content = %q, want %q", got["Content"], "Привет")
+	}
+}
+
+func TestProjectionErrors(t *testing.T) {
+	api := newTestAPI(t, nil)
+	id := api.createNote("Title", "text")
+
+	for _, expr := range []string{"id,nope", "created_at[0:2]", "content[0:2", "content[5:1]"} {
+		rec := api.do(http.MethodGet, "/api/v1/notes/"+id+"?projection="+expr, "")
+		expectStatus(t, rec, http.StatusBadRequest)
+		if !strings.Contains(rec.Body.String(), "position") {
+			t.Errorf("%s: error %s does not point at a position", expr, rec.Body)
+		}
+	}
+
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/notes?projection=title&content=false", ""), http.StatusBadRequest)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"example.com/notes-api/internal/core"
+	"example.com/notes-api/internal/repo"
+	"github.com/go-chi/chi/v5"
+)
+
+type SnapshotResponse struct {
+	Token     string     `json:"token"`
+	Note      noteView   `json:"note"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateSnapshot фиксирует текущее состояние заметки; ?ttl=24h задает срок жизни снимка
+func (h *Handler) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	id, err := h.parseID(chi.URLParam(r, "id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid note ID")
+		return
+	}
+
+	var ttl time.Duration
+	if ttlStr := r.URL.Query().Get("ttl"); ttlStr != "" {
+		ttl, err = time.ParseDuration(ttlStr)
+		if err != nil || ttl <= 0 {
+			respondWithError(w, http.StatusBadRequest, "TTL must be a positive duration, e.g. 24h")
+			return
+		}
+	}
+
+	note, err := h.Repo.GetByID(id)
+	if err != nil {
+		if err == repo.ErrNoteNotFound {
+			respondWithError(w, http.StatusNotFound, "Note not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get note")
+		}
+		return
+	}
+
+	snapshot, err := h.Snapshots.Create(*note, ttl)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create snapshot")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, h.snapshotView(snapshot))
+}
+
+// GetSnapshot возвращает снимок по токену, независимо от последующих правок заметки
+func (h *Handler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := h.Snapshots.GetByToken(chi.URLParam(r, "token"))
+	if err != nil {
+		if err == repo.ErrSnapshotNotFound {
+			respondWithError(w, http.StatusNotFound, "Snapshot not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get snapshot")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, h.snapshotView(snapshot))
+}
+
+func (h *Handler) snapshotView(s *core.Snapshot) SnapshotResponse {
+	return SnapshotResponse{
+		Token:     s.Token,
+		Note:      h.view(s.Note),
+		CreatedAt: s.CreatedAt,
+		ExpiresAt: s.ExpiresAt,
+	}
+}
@@ -0,0 +1,59 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+type snapshotResponse struct {
+	Token string `json:"token"`
+	Note  struct {
+		Title   string
+		Content string
+	} `json:"note"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func TestSnapshotKeepsOriginalContent(t *testing.T) {
+	api := newTestAPI(t, nil)
+	id := api.createNote("title", "original")
+
+	rec := api.do(http.MethodPost, "/api/v1/notes/"+id+"/snapshot", "")
+	expectStatus(t, rec, http.StatusCreated)
+	var created snapshotResponse
+	decodeJSON(t, rec, &created)
+	if created.Token == "" || created.ExpiresAt != nil {
+		t.Fatalf("snapshot = %+v, want a token without expiry", created)
+	}
+
+	expectStatus(t, api.do(http.MethodPatch, "/api/v1/notes/"+id, `{"content":"edited"}`), http.StatusOK)
+
+	rec = api.do(http.MethodGet, "/api/v1/snapshots/"+created.Token, "")
+	expectStatus(t, rec, http.StatusOK)
+	var got snapshotResponse
+	decodeJSON(t, rec, &got)
+	if got.Note.Content != "original" {
+		t.Fatalf("snapshot content = %q, want the original", got.Note.Content)
+	}
+
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/snapshots/unknown", ""), http.StatusNotFound)
+}
+
+func TestSnapshotTTL(t *testing.T) {
+	api := newTestAPI(t, nil)
+	id := api.createNote("title", "")
+
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/notes/"+id+"/snapshot?ttl=-1h", ""), http.StatusBadRequest)
+
+	rec := api.do(http.MethodPost, "/api/v1/notes/"+id+"/snapshot?ttl=1ms", "")
+	expectStatus(t, rec, http.StatusCreated)
+	var created snapshotResponse
+	decodeJSON(t, rec, &created)
+	if created.ExpiresAt == nil {
+		t.Fatal("snapshot with ttl has no expires_at")
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/snapshots/"+created.Token, ""), http.StatusNotFound)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"example.com/notes-api/internal/core"
+	"example.com/notes-api/internal/repo"
+	"github.com/go-chi/chi/v5"
+)
+
+var viewName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// viewFilterKeys - что можно сохранить в представлении: фильтры GET /notes (min_words, max_words)
+// и порядок sort с теми же значениями, что у GET /notes/toc
+var viewFilterKeys = map[string]bool{"min_words": true, "max_words": true, "sort": true}
+
+type CreateViewRequest struct {
+	Name   string            `json:"name"`
+	Filter map[string]string `json:"filter"`
+}
+
+type ViewResponse struct {
+	Name      string            `json:"name"`
+	Filter    map[string]string `json:"filter"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// CreateView сохраняет фильтр списка заметок под именем
+func (h *Handler) CreateView(w http.ResponseWriter, r *http.Request) {
+	var req CreateViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	if !viewName.MatchString(req.Name) {
+		respondWithError(w, http.StatusBadRequest, "Name must be 1-64 letters, digits, '-' or '_'")
+		return
+	}
+	for key := range req.Filter {
+		if !viewFilterKeys[key] {
+			respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Unknown filter parameter %q", key))
+			return
+		}
+	}
+	if _, err := parseViewFilter(req.Filter); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := sortNotes(nil, req.Filter["sort"]); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	view, err := h.Views.Create(req.Name, req.Filter)
+	if err != nil {
+		if err == repo.ErrViewExists {
+			respondWithError(w, http.StatusConflict, "View already exists")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to create view")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, viewResponse(*view))
+}
+
+func (h *Handler) GetViews(w http.ResponseWriter, r *http.Request) {
+	views := h.Views.GetAll()
+	out := make([]ViewResponse, 0, len(views))
+	for _, v := range views {
+		out = append(out, viewResponse(v))
+	}
+
+	respondWithJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) DeleteView(w http.ResponseWriter, r *http.Request) {
+	if err := h.Views.Delete(chi.URLParam(r, "name")); err != nil {
+		if err == repo.ErrViewNotFound {
+			respondWithError(w, http.StatusNotFound, "View not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to delete view")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, SuccessResponse{Message: "View deleted successfully"})
+}
+
+// GetViewNotes возвращает заметки, подходящие под сохраненный фильтр.
+// Без sort заметки идут по ID, как в GET /notes
+func (h *Handler) GetViewNotes(w http.ResponseWriter, r *http.Request) {
+	view, err := h.Views.GetByName(chi.URLParam(r, "name"))
+	if err != nil {
+		if err == repo.ErrViewNotFound {
+			respondWithError(w, http.StatusNotFound, "View not found")
+		} else {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get view")
+		}
+		return
+	}
+
+	filter, err := parseViewFilter(view.Filter)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Stored view filter is invalid")
+		return
+	}
+
+	field := view.Filter["sort"]
+	if field == "" {
+		// порядок ID совпадает с порядком обхода, поэтому заметки не собираются в память
+		cursor := h.Repo.Cursor()
+		streamJSONArray(w, func() (interface{}, bool) {
+			n, ok := filter.next(cursor)
+			if !ok {
+				return nil, false
+			}
+			return h.view(n), true
+		})
+		return
+	}
+
+	notes, err := h.Repo.GetAll()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get notes")
+		return
+	}
+
+	notes = filter.apply(notes)
+	if err := sortNotes(notes, field); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Stored view filter is invalid")
+		return
+	}
+
+	streamJSONArray(w, func() (interface{}, bool) {
+		if len(notes) == 0 {
+			return nil, false
+		}
+		n := notes[0]
+		notes = notes[1:]
+		return h.view(n), true
+	})
+}
+
+func parseViewFilter(filter map[string]string) (noteFilter, error) {
+	q := make(url.Values, len(filter))
+	for k, v := range filter {
+		q.Set(k, v)
+	}
+	return parseNoteFilter(q)
+}
+
+func viewResponse(v core.View) ViewResponse {
+	return ViewResponse{Name: v.Name, Filter: v.Filter, CreatedAt: v.CreatedAt}
+}
@@ -0,0 +1,69 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/notes-api/internal/config"
+	"example.com/notes-api/internal/core"
+	"example.com/notes-api/internal/http/handlers"
+	"example.com/notes-api/internal/repo"
+)
+
+func withViews(h *handlers.Handler, cfg *config.Config) {
+	h.Views = repo.NewViewRepoMem()
+}
+
+func viewTitles(t *testing.T, api *testAPI, target string) string {
+	t.Helper()
+	rec := api.do(http.MethodGet, target, "")
+	expectStatus(t, rec, http.StatusOK)
+	var notes []struct{ Title string }
+	decodeJSON(t, rec, &notes)
+	titles := make([]string, 0, len(notes))
+	for _, n := range notes {
+		titles = append(titles, n.Title)
+	}
+	return strings.Join(titles, ",")
+}
+
+func TestViewNotesMatchListing(t *testing.T) {
+	api := newTestAPI(t, withViews)
+	seedWordCounts(api)
+
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/views", `{"name":"long","filter":{"min_words":"3","max_words":"5"}}`), http.StatusCreated)
+	if got, want := viewTitles(t, api, "/api/v1/views/long/notes"), viewTitles(t, api, "/api/v1/notes?min_words=3&max_words=5"); got != want || got != "www,wwwww" {
+		t.Fatalf("view notes = %s, GET /notes = %s; want www,wwwww from both", got, want)
+	}
+
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/views", `{"name":"long","filter":{}}`), http.StatusConflict)
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/views", `{"name":"bad","filter":{"q":"x"}}`), http.StatusBadRequest)
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/views", `{"name":"bad","filter":{"min_words":"x"}}`), http.StatusBadRequest)
+	expectStatus(t, api.do(http.MethodGet, "/api/v1/views/missing/notes", ""), http.StatusNotFound)
+}
+
+func TestViewNotesDefaultToIDOrder(t *testing.T) {
+	api := newTestAPI(t, withViews)
+	// ID и время создания упорядочены в разные стороны
+	now := time.Now()
+	for _, n := range []core.Note{
+		{ID: 1, Title: "newer", CreatedAt: now},
+		{ID: 2, Title: "older", CreatedAt: now.Add(-time.Hour)},
+	} {
+		if _, err := api.handler.Repo.Restore(n, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/views", `{"name":"all","filter":{}}`), http.StatusCreated)
+	expectStatus(t, api.do(http.MethodPost, "/api/v1/views", `{"name":"by-date","filter":{"sort":"created_at"}}`), http.StatusCreated)
+
+	if got, want := viewTitles(t, api, "/api/v1/views/all/notes"), viewTitles(t, api, "/api/v1/notes"); got != want || got != "newer,older" {
+		t.Fatalf("view without sort = %s, GET /notes = %s; want ID order from both", got, want)
+	}
+	if got := viewTitles(t, api, "/api/v1/views/by-date/notes"); got != "older,newer" {
+		t.Fatalf("view sorted by created_at = %s, want older,newer", got)
+	}
+}
@@ -0,0 +1,33 @@
+package httpx
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Logger пишет запросы в лог через middleware.Logger, пропуская пути,
+// подходящие под шаблоны exclude (например, /health и /metrics)
+func Logger(exclude []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		logged := middleware.Logger(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if matchesAny(r.URL.Path, exclude) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			logged.ServeHTTP(w, r)
+		})
+	}
+}
+
+func matchesAny(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
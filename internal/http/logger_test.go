@@ -0,0 +1,33 @@
+package httpx
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+func TestLoggerSkipsExcludedPaths(t *testing.T) {
+	var buf bytes.Buffer
+	saved := middleware.DefaultLogger
+	middleware.DefaultLogger = middleware.RequestLogger(&middleware.DefaultLogFormatter{
+		Logger:  log.New(&buf, "", 0),
+		NoColor: true,
+	})
+	t.Cleanup(func() { middleware.DefaultLogger = saved })
+
+	handler := Logger([]string{"/health", "/debug/*"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, path := range []string{"/health", "/debug/vars", "/api/v1/notes"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "/api/v1/notes") {
+		t.Fatalf("log = %q, want a single line for /api/v1/notes", buf.String())
+	}
+}
@@ -0,0 +1,151 @@
+package httpx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+
+	"example.com/notes-api/internal/config"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const requestIDHeader = "X-Request-Id"
+
+// RequestID берет ID запроса из заголовка или генерирует новый в заданном формате.
+// ID кладется в контекст (его видит middleware.Logger) и возвращается в ответе.
+func RequestID(format string, maxLen int) func(http.Handler) http.Handler {
+	generate := newRequestIDGenerator(format)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestIDHeader)
+			if !validRequestID(id, maxLen) {
+				id = generate()
+			}
+
+			w.Header().Set(requestIDHeader, id)
+			ctx := context.WithValue(r.Context(), middleware.RequestIDKey, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// validRequestID пропускает только короткие ID из печатных ASCII-символов,
+// чтобы клиент не мог внедрить переводы строк и прочий мусор в логи
+func validRequestID(id string, maxLen int) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestIDGenerator(format string) func() string {
+	switch format {
+	case config.RequestIDBase62:
+		return newBase62ID
+	case config.RequestIDULID:
+		var g ulidGenerator
+		return g.next
+	default:
+		return newUUIDv4
+	}
+}
+
+func newUUIDv4() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf[:])
+}
+
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+func newBase62ID() string {
+	var b [12]byte
+	rand.Read(b[:])
+
+	out := make([]byte, len(b))
+	for i, c := range b {
+		// небольшой перекос распределения для ID трассировки не важен
+		out[i] = base62Alphabet[int(c)%len(base62Alphabet)]
+	}
+	return string(out)
+}
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator выдает монотонные ULID: в пределах одной миллисекунды
+// случайная часть увеличивается на 1, поэтому ID сортируются по порядку выдачи
+type ulidGenerator struct {
+	mu      sync.Mutex
+	lastMs  uint64
+	entropy [10]byte
+}
+
+func (g *ulidGenerator) next() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= g.lastMs {
+		g.increment()
+		ms = g.lastMs
+	} else {
+		g.lastMs = ms
+		rand.Read(g.entropy[:])
+	}
+
+	var raw [16]byte
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(raw[0:6], ts[2:])
+	copy(raw[6:], g.entropy[:])
+
+	return encodeULID(raw)
+}
+
+func (g *ulidGenerator) increment() {
+	for i := len(g.entropy) - 1; i >= 0; i-- {
+		g.entropy[i]++
+		if g.entropy[i] != 0 {
+			return
+		}
+	}
+	// переполнение случайной части - переходим к следующей миллисекунде
+	g.lastMs++
+}
+
+// encodeULID кодирует 128 бит в 26 символов Crockford base32
+func encodeULID(raw [16]byte) string {
+	var out [26]byte
+	hi := binary.BigEndian.Uint64(raw[0:8])
+	lo := binary.BigEndian.Uint64(raw[8:16])
+
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
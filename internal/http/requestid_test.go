@@ -0,0 +1,91 @@
+package httpx
+
+import (
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/notes-api/internal/config"
+)
+
+func TestRequestIDULIDSortable(t *testing.T) {
+	handler := RequestID(config.RequestIDULID, 64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	var ids []string
+	for i := 0; i < 500; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		id := rec.Header().Get(requestIDHeader)
+		if len(id) != 26 {
+			t.Fatalf("ULID %q has length %d, want 26", id, len(id))
+		}
+		ids = append(ids, id)
+	}
+
+	if !sort.StringsAreSorted(ids) {
+		t.Fatal("ULIDs are not sorted in issue order")
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] == ids[i-1] {
+			t.Fatalf("duplicate ULID %q", ids[i])
+		}
+	}
+}
+
+func TestULIDEntropyOverflowMovesToNextMillisecond(t *testing.T) {
+	future := uint64(time.Now().Add(time.Hour).UnixMilli())
+	g := &ulidGenerator{lastMs: future}
+	for i := range g.entropy {
+		g.entropy[i] = 0xff
+	}
+
+	// последний ID, выданный в этой миллисекунде: случайная часть исчерпана
+	var last, ts [16]byte
+	binary.BigEndian.PutUint64(ts[:8], future)
+	copy(last[0:6], ts[2:8])
+	copy(last[6:], g.entropy[:])
+	prev := encodeULID(last)
+
+	next := g.next()
+	if next <= prev {
+		t.Fatalf("ULID after overflow %q is not greater than %q", next, prev)
+	}
+	if g.lastMs != future+1 {
+		t.Fatalf("lastMs = %d, want %d", g.lastMs, future+1)
+	}
+}
+
+func TestRequestIDReplacesInvalidHeader(t *testing.T) {
+	handler := RequestID(config.RequestIDUUID, 64)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name, id string
+		echoed   bool
+	}{
+		{"valid", "client-trace-42", true},
+		{"too long", strings.Repeat("a", 65), false},
+		{"newline", "abc\ninjected", false},
+		{"control character", "abc\x01", false},
+		{"non-ASCII", "трасса", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header[requestIDHeader] = []string{tt.id}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(requestIDHeader)
+			if tt.echoed && got != tt.id {
+				t.Fatalf("ID = %q, want echoed %q", got, tt.id)
+			}
+			if !tt.echoed && (got == tt.id || len(got) != 36) {
+				t.Fatalf("ID = %q, want a freshly generated UUID", got)
+			}
+		})
+	}
+}
@@ -3,29 +3,72 @@ package httpx
 import (
 	"net/http"
 
+	"example.com/notes-api/internal/config"
 	"example.com/notes-api/internal/http/handlers"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-func NewRouter(h *handlers.Handler) *chi.Mux {
+func NewRouter(h *handlers.Handler, cfg config.Config) *chi.Mux {
 	r := chi.NewRouter()
 
-	r.Use(middleware.Logger)
+	r.Use(RequestID(cfg.RequestIDFormat, cfg.RequestIDMaxLen))
+	r.Use(Logger(cfg.LogExclude))
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.RequestID)
 
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Route("/notes", func(r chi.Router) {
 			r.Post("/", h.CreateNote)
 			r.Get("/", h.GetAllNotes)
+			r.Get("/timeline", h.GetTimeline)
+			r.Get("/combined.md", h.GetCombinedMarkdown)
+			r.Get("/toc", h.GetTOC)
+			r.Get("/lint", h.LintNotes)
+			r.Get("/search/explain", h.ExplainSearch)
+			r.Get("/export.tar.gz", h.ExportTarGz)
+			r.Get("/export.json", h.ExportJSON)
+			r.Post("/import", h.ImportNotes)
+			r.Post("/diff-export", h.DiffExport)
+			r.Get("/by-hash/{hash}", h.GetNotesByHash)
+			r.Post("/stream-import", h.StreamImport)
+			r.Get("/oldest", h.GetOldestNotes)
+			r.Get("/untouched", h.GetUntouchedNotes)
+			r.Get("/storage", h.GetStorage)
+			r.Post("/repair-links", h.RepairLinks)
+			r.Get("/with-links", h.GetNotesWithLinks)
+			r.Post("/delta", h.GetDelta)
 			r.Route("/{id}", func(r chi.Router) {
 				r.Get("/", h.GetNote)
 				r.Patch("/", h.PatchNote)
 				r.Delete("/", h.DeleteNote)
-
+				r.Get("/size", h.GetNoteSize)
+				r.Get("/checksum", h.GetNoteChecksum)
+				r.Post("/patch-preview", h.PreviewPatch)
+				r.Get("/audit", h.GetNoteAudit)
+				r.Post("/snapshot", h.CreateSnapshot)
+				r.Get("/graph", h.GetNoteGraph)
 			})
 		})
+
+		r.Get("/snapshots/{token}", h.GetSnapshot)
+
+		r.Route("/views", func(r chi.Router) {
+			r.Post("/", h.CreateView)
+			r.Get("/", h.GetViews)
+			r.Delete("/{name}", h.DeleteView)
+			r.Get("/{name}/notes", h.GetViewNotes)
+		})
+
+		if cfg.AdminAPIKey != "" {
+			r.Route("/admin", func(r chi.Router) {
+				r.Use(RequireAPIKey(cfg.AdminAPIKey))
+				r.Post("/reindex", h.AdminReindex)
+				r.Get("/backups", h.AdminListBackups)
+				r.Post("/restore", h.AdminRestore)
+				r.Get("/config", configHandler(cfg))
+				r.Post("/normalize", h.AdminNormalize)
+			})
+		}
 	})
 
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -34,5 +77,7 @@ func NewRouter(h *handlers.Handler) *chi.Mux {
 		w.Write([]byte(`{"status": "ok"}`))
 	})
 
+	r.Get("/routes", routesHandler(r))
+
 	return r
 }
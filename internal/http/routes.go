@@ -0,0 +1,56 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"example.com/notes-api/internal/config"
+	"github.com/go-chi/chi/v5"
+)
+
+type routeInfo struct {
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+}
+
+// routesHandler отдает список зарегистрированных маршрутов, собранный обходом роутера при каждом запросе
+func routesHandler(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list := []routeInfo{}
+		err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			list = append(list, routeInfo{Method: method, Pattern: route})
+			return nil
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to list routes"})
+			return
+		}
+
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Pattern != list[j].Pattern {
+				return list[i].Pattern < list[j].Pattern
+			}
+			return list[i].Method < list[j].Method
+		})
+
+		w.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(list)
+	}
+}
+
+// configHandler отдает действующую конфигурацию без секретов
+func configHandler(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(cfg.Redacted())
+	}
+}
@@ -0,0 +1,92 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/notes-api/internal/config"
+	"example.com/notes-api/internal/http/handlers"
+	"example.com/notes-api/internal/repo"
+)
+
+func testConfig() config.Config {
+	return config.Config{RequestIDFormat: config.RequestIDUUID, RequestIDMaxLen: 64}
+}
+
+func getJSON(t *testing.T, h http.Handler, target string, v interface{}, headers ...string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("decode %s: %v", target, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestRoutesListsNoteRoutes(t *testing.T) {
+	r := NewRouter(&handlers.Handler{Repo: repo.NewNoteRepoMem()}, testConfig())
+
+	var routes []routeInfo
+	if code := getJSON(t, r, "/routes", &routes); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+
+	listed := make(map[routeInfo]bool, len(routes))
+	for _, route := range routes {
+		listed[route] = true
+	}
+	for _, want := range []routeInfo{
+		{http.MethodGet, "/api/v1/notes/"},
+		{http.MethodPost, "/api/v1/notes/"},
+		{http.MethodGet, "/api/v1/notes/{id}/"},
+		{http.MethodPatch, "/api/v1/notes/{id}/"},
+		{http.MethodDelete, "/api/v1/notes/{id}/"},
+		{http.MethodGet, "/health"},
+		{http.MethodGet, "/routes"},
+	} {
+		if !listed[want] {
+			t.Errorf("route %s %s is missing", want.Method, want.Pattern)
+		}
+	}
+	for route := range listed {
+		if route.Pattern == "/api/v1/admin/reindex" {
+			t.Error("admin routes are listed although no admin key is configured")
+		}
+	}
+}
+
+func TestAdminConfigRedactsSecrets(t *testing.T) {
+	cfg := testConfig()
+	cfg.AdminAPIKey = "super-secret-key"
+	cfg.IDSalt = "salt"
+	cfg.BackupInterval = time.Hour
+	r := NewRouter(&handlers.Handler{Repo: repo.NewNoteRepoMem()}, cfg)
+
+	var shown map[string]interface{}
+	if code := getJSON(t, r, "/api/v1/admin/config", &shown, "X-API-Key", cfg.AdminAPIKey); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	for key, want := range map[string]interface{}{
+		"AdminAPIKey":     "[redacted]",
+		"IDSalt":          "[redacted]",
+		"RequestIDMaxLen": float64(64),
+		"BackupInterval":  "1h0m0s",
+	} {
+		if shown[key] != want {
+			t.Errorf("%s = %v, want %v", key, shown[key], want)
+		}
+	}
+	if body, _ := json.Marshal(shown); strings.Contains(string(body), cfg.AdminAPIKey) {
+		t.Fatalf("config leaks the admin key: %s", body)
+	}
+}
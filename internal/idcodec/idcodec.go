@@ -0,0 +1,109 @@
+// Package idcodec обратимо превращает последовательные int64 ID в короткие непрозрачные строки.
+package idcodec
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+const (
+	alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+	// 62^11 > 2^64, поэтому любое uint64 помещается в 11 символов
+	valueLen    = 11
+	checksumLen = 2
+	rounds      = 4
+)
+
+var ErrInvalidID = errors.New("invalid id")
+
+// Codec перемешивает ID ключевой перестановкой (сеть Фейстеля на HMAC-SHA256),
+// кодирует в base62 и добавляет контрольные символы, чтобы отсеивать подделанные строки
+type Codec struct {
+	key []byte
+}
+
+func New(salt string) *Codec {
+	return &Codec{key: []byte(salt)}
+}
+
+// Encode возвращает строковое представление ID
+func (c *Codec) Encode(id int64) string {
+	v := c.permute(uint64(id))
+
+	var buf [valueLen + checksumLen]byte
+	for i := valueLen - 1; i >= 0; i-- {
+		buf[i] = alphabet[v%62]
+		v /= 62
+	}
+	sum := c.checksum(buf[:valueLen])
+	buf[valueLen] = alphabet[sum/62]
+	buf[valueLen+1] = alphabet[sum%62]
+
+	return string(buf[:])
+}
+
+// Decode восстанавливает ID; для строк, которые не выдавал Encode, возвращает ErrInvalidID
+func (c *Codec) Decode(s string) (int64, error) {
+	if len(s) != valueLen+checksumLen {
+		return 0, ErrInvalidID
+	}
+
+	var v uint64
+	for i := 0; i < valueLen; i++ {
+		d := strings.IndexByte(alphabet, s[i])
+		if d < 0 {
+			return 0, ErrInvalidID
+		}
+		next := v*62 + uint64(d)
+		if next/62 != v {
+			return 0, ErrInvalidID
+		}
+		v = next
+	}
+
+	hi, lo := strings.IndexByte(alphabet, s[valueLen]), strings.IndexByte(alphabet, s[valueLen+1])
+	if hi < 0 || lo < 0 || hi*62+lo != c.checksum([]byte(s[:valueLen])) {
+		return 0, ErrInvalidID
+	}
+
+	return int64(c.unpermute(v)), nil
+}
+
+func (c *Codec) permute(v uint64) uint64 {
+	l, r := uint32(v>>32), uint32(v)
+	for i := 0; i < rounds; i++ {
+		l, r = r, l^c.round(i, r)
+	}
+	return uint64(l)<<32 | uint64(r)
+}
+
+func (c *Codec) unpermute(v uint64) uint64 {
+	l, r := uint32(v>>32), uint32(v)
+	for i := rounds - 1; i >= 0; i-- {
+		l, r = r^c.round(i, l), l
+	}
+	return uint64(l)<<32 | uint64(r)
+}
+
+func (c *Codec) round(i int, half uint32) uint32 {
+	var msg [5]byte
+	msg[0] = byte(i)
+	binary.BigEndian.PutUint32(msg[1:], half)
+	return binary.BigEndian.Uint32(c.mac(msg[:]))
+}
+
+// checksum - значение в диапазоне [0, 62*62)
+func (c *Codec) checksum(value []byte) int {
+	sum := c.mac(append([]byte{'c'}, value...))
+	return int(binary.BigEndian.Uint32(sum) % (62 * 62))
+}
+
+func (c *Codec) mac(msg []byte) []byte {
+	m := hmac.New(sha256.New, c.key)
+	m.Write(msg)
+	return m.Sum(nil)
+}
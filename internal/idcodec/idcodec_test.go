@@ -0,0 +1,45 @@
+package idcodec
+
+import "testing"
+
+func TestRoundTrip(t *testing.T) {
+	c := New("salt")
+	seen := make(map[string]bool)
+	for _, id := range []int64{0, 1, 2, 3, 42, 1 << 31, 1<<62 + 12345, -1} {
+		s := c.Encode(id)
+		if len(s) != valueLen+checksumLen {
+			t.Fatalf("Encode(%d) = %q, want %d characters", id, s, valueLen+checksumLen)
+		}
+		if seen[s] {
+			t.Fatalf("Encode(%d) = %q collides with another ID", id, s)
+		}
+		seen[s] = true
+
+		got, err := c.Decode(s)
+		if err != nil || got != id {
+			t.Fatalf("Decode(Encode(%d)) = %d, %v", id, got, err)
+		}
+	}
+}
+
+func TestDecodeRejectsTamperedIDs(t *testing.T) {
+	c := New("salt")
+	s := c.Encode(7)
+
+	tampered := []byte(s)
+	if tampered[0] == 'A' {
+		tampered[0] = 'B'
+	} else {
+		tampered[0] = 'A'
+	}
+
+	for _, bad := range []string{"", "7", s[:len(s)-1], s + "0", string(tampered), "zzzzzzzzzzzzz", "!!!!!!!!!!!!!"} {
+		if _, err := c.Decode(bad); err != ErrInvalidID {
+			t.Errorf("Decode(%q): err = %v, want ErrInvalidID", bad, err)
+		}
+	}
+
+	if _, err := New("other salt").Decode(s); err != ErrInvalidID {
+		t.Errorf("ID from another salt decoded: err = %v", err)
+	}
+}
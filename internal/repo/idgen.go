@@ -0,0 +1,34 @@
+package repo
+
+// IDGenerator выдает ID для новых заметок. NoteRepoMem вызывает Next под своей
+// блокировкой, поэтому реализации не обязаны быть потокобезопасными сами по себе.
+type IDGenerator interface {
+	Next() int64
+}
+
+// idObserver - необязательное расширение IDGenerator: генератор узнает о заметках,
+// сохраненных с готовыми ID (восстановление, импорт), чтобы не выдать их повторно
+type idObserver interface {
+	Observe(id int64)
+}
+
+// SequentialIDs - генератор по умолчанию: 1, 2, 3, ...
+type SequentialIDs struct {
+	next int64
+}
+
+func (g *SequentialIDs) Next() int64 {
+	if g.next < 1 {
+		g.next = 1
+	}
+	id := g.next
+	g.next++
+	return id
+}
+
+// Observe сдвигает счетчик за уже занятый ID
+func (g *SequentialIDs) Observe(id int64) {
+	if id >= g.next {
+		g.next = id + 1
+	}
+}
@@ -0,0 +1,52 @@
+package repo
+
+import (
+	"testing"
+	"time"
+
+	"example.com/notes-api/internal/core"
+)
+
+// fixedIDs выдает заранее заданные ID по порядку
+type fixedIDs struct {
+	ids []int64
+}
+
+func (g *fixedIDs) Next() int64 {
+	id := g.ids[0]
+	g.ids = g.ids[1:]
+	return id
+}
+
+func TestCreateUsesGenerator(t *testing.T) {
+	r := NewNoteRepoMemWithIDs(&fixedIDs{ids: []int64{100, 200, 100}})
+
+	for _, want := range []int64{100, 200} {
+		id, err := r.Create(core.Note{Title: "n"})
+		if err != nil || id != want {
+			t.Fatalf("Create() = %d, %v; want %d", id, err, want)
+		}
+		if n, err := r.GetByID(want); err != nil || n.ID != want {
+			t.Fatalf("GetByID(%d) = %+v, %v", want, n, err)
+		}
+	}
+
+	if _, err := r.Create(core.Note{Title: "dup"}); err != ErrIDCollision {
+		t.Fatalf("Create() with a taken ID: err = %v, want ErrIDCollision", err)
+	}
+	if n, _ := r.GetByID(100); n.Title != "n" {
+		t.Fatalf("collision overwrote note 100: %+v", n)
+	}
+}
+
+func TestSequentialIDsSkipRestoredIDs(t *testing.T) {
+	r := NewNoteRepoMem()
+	if _, err := r.Restore(core.Note{ID: 5, Title: "restored", CreatedAt: time.Now()}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := r.Create(core.Note{Title: "new"})
+	if err != nil || id != 6 {
+		t.Fatalf("Create() after restoring ID 5 = %d, %v; want 6", id, err)
+	}
+}
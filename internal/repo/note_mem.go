@@ -1,7 +1,11 @@
 package repo
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -9,19 +13,47 @@ import (
 )
 
 var (
-	ErrNoteNotFound = errors.New("note not found")
+	ErrNoteNotFound       = errors.New("note not found")
+	ErrInvalidGranularity = errors.New("invalid granularity")
+	ErrNoteExists         = errors.New("note already exists")
+	ErrNoteHasDependents  = errors.New("note is included by other notes")
+	ErrIDCollision        = errors.New("generated note ID is already taken")
+)
+
+// DependentsPolicy - как удаление поступает с заметками, подключающими удаляемую через {{include:id}}
+type DependentsPolicy int
+
+const (
+	DependentsIgnore DependentsPolicy = iota // удалить, зависимые не трогать
+	DependentsBlock                          // не удалять, если зависимые есть
+	DependentsMark                           // удалить и пометить макросы в зависимых
+)
+
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
 )
 
 type NoteRepoMem struct {
-	mu    sync.RWMutex
-	notes map[int64]*core.Note
-	next  int64
+	mu     sync.RWMutex
+	notes  map[int64]*core.Note
+	byHash map[string]map[int64]struct{}
+	audit  map[int64][]core.AuditEntry
+	ids    IDGenerator
 }
 
 func NewNoteRepoMem() *NoteRepoMem {
+	return NewNoteRepoMemWithIDs(&SequentialIDs{})
+}
+
+// NewNoteRepoMemWithIDs создает хранилище, которое берет ID новых заметок из gen
+func NewNoteRepoMemWithIDs(gen IDGenerator) *NoteRepoMem {
 	return &NoteRepoMem{
-		notes: make(map[int64]*core.Note),
-		next:  1,
+		notes:  make(map[int64]*core.Note),
+		byHash: make(map[string]map[int64]struct{}),
+		audit:  make(map[int64][]core.AuditEntry),
+		ids:    gen,
 	}
 }
 
@@ -29,15 +61,42 @@ func (r *NoteRepoMem) Create(n core.Note) (int64, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	n.ID = r.next
+	n.ID = r.ids.Next()
+	if _, taken := r.notes[n.ID]; taken {
+		return 0, ErrIDCollision
+	}
 	n.CreatedAt = time.Now()
 	n.UpdatedAt = nil
 	r.notes[n.ID] = &n
-	r.next++
+	r.indexHash(n.ID, n.Content)
+	r.recordAudit(n.ID, core.AuditCreated, nil, n.CreatedAt)
 
 	return n.ID, nil
 }
 
+// Restore сохраняет заметку как есть, с ее ID и временными метками.
+// Если заметка с таким ID уже есть, она заменяется только при overwrite, иначе - ErrNoteExists.
+// replaced сообщает, была ли заменена существующая заметка.
+func (r *NoteRepoMem) Restore(n core.Note, overwrite bool) (replaced bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, exists := r.notes[n.ID]
+	if exists {
+		if !overwrite {
+			return false, ErrNoteExists
+		}
+		r.unindexHash(old.ID, old.Content)
+	}
+
+	r.notes[n.ID] = &n
+	r.indexHash(n.ID, n.Content)
+	r.recordAudit(n.ID, core.AuditRestored, nil, time.Now())
+	r.observeID(n.ID)
+
+	return exists, nil
+}
+
 func (r *NoteRepoMem) GetByID(id int64) (*core.Note, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -60,9 +119,67 @@ func (r *NoteRepoMem) GetAll() ([]core.Note, error) {
 		notes = append(notes, *note)
 	}
 
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i].ID < notes[j].ID
+	})
+
 	return notes, nil
 }
 
+// NoteCursor обходит заметки в порядке ID, не копируя их все сразу.
+// При создании запоминается только список ID, а каждая заметка читается
+// под блокировкой в момент Next; удаленные за время обхода пропускаются.
+type NoteCursor struct {
+	repo *NoteRepoMem
+	ids  []int64
+}
+
+// Cursor начинает обход заметок в порядке ID
+func (r *NoteRepoMem) Cursor() *NoteCursor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]int64, 0, len(r.notes))
+	for id := range r.notes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return &NoteCursor{repo: r, ids: ids}
+}
+
+// Next возвращает следующую заметку; false - заметки закончились
+func (c *NoteCursor) Next() (core.Note, bool) {
+	for len(c.ids) > 0 {
+		id := c.ids[0]
+		c.ids = c.ids[1:]
+
+		c.repo.mu.RLock()
+		note, exists := c.repo.notes[id]
+		var n core.Note
+		if exists {
+			n = *note
+		}
+		c.repo.mu.RUnlock()
+
+		if exists {
+			return n, true
+		}
+	}
+	return core.Note{}, false
+}
+
+// Each вызывает fn для заметок в порядке ID, пока fn возвращает true
+func (r *NoteRepoMem) Each(fn func(core.Note) bool) {
+	c := r.Cursor()
+	for {
+		n, ok := c.Next()
+		if !ok || !fn(n) {
+			return
+		}
+	}
+}
+
 func (r *NoteRepoMem) UpdatePartial(id int64, updates map[string]interface{}) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -72,28 +189,389 @@ func (r *NoteRepoMem) UpdatePartial(id int64, updates map[string]interface{}) er
 		return ErrNoteNotFound
 	}
 
+	r.update(note, updates, time.Now())
+
+	return nil
+}
+
+// PreviewPartial возвращает заметку в том виде, какой она станет после UpdatePartial, не сохраняя изменений
+func (r *NoteRepoMem) PreviewPartial(id int64, updates map[string]interface{}) (*core.Note, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	note, exists := r.notes[id]
+	if !exists {
+		return nil, ErrNoteNotFound
+	}
+
+	preview := *note
+	applyUpdates(&preview, updates, time.Now())
+
+	return &preview, nil
+}
+
+// update применяет изменения к хранимой заметке, поддерживая индексы и журнал
+func (r *NoteRepoMem) update(note *core.Note, updates map[string]interface{}, now time.Time) {
+	r.unindexHash(note.ID, note.Content)
+	changed := applyUpdates(note, updates, now)
+	r.indexHash(note.ID, note.Content)
+	r.recordAudit(note.ID, core.AuditUpdated, changed, now)
+}
+
+// applyUpdates меняет поля заметки и возвращает имена полей, значение которых изменилось
+func applyUpdates(note *core.Note, updates map[string]interface{}, now time.Time) []string {
+	var changed []string
+
 	if title, ok := updates["title"].(string); ok && title != "" {
+		if title != note.Title {
+			changed = append(changed, "title")
+		}
 		note.Title = title
 	}
 
 	if content, ok := updates["content"].(string); ok {
+		if content != note.Content {
+			changed = append(changed, "content")
+		}
 		note.Content = content
 	}
 
-	now := time.Now()
 	note.UpdatedAt = &now
 
-	return nil
+	return changed
 }
 
 func (r *NoteRepoMem) Delete(id int64) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.notes[id]; !exists {
+	note, exists := r.notes[id]
+	if !exists {
 		return ErrNoteNotFound
 	}
 
+	r.unindexHash(id, note.Content)
+	r.recordAudit(id, core.AuditDeleted, nil, time.Now())
 	delete(r.notes, id)
 	return nil
 }
+
+// DeleteWithDependents удаляет заметку с учетом заметок, которые ее подключают.
+// Возвращает ID зависимых заметок; при DependentsBlock и непустом списке
+// заметка не удаляется и возвращается ErrNoteHasDependents. ids - формат ID в макросах.
+func (r *NoteRepoMem) DeleteWithDependents(id int64, policy DependentsPolicy, ids core.RefIDs) ([]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	note, exists := r.notes[id]
+	if !exists {
+		return nil, ErrNoteNotFound
+	}
+
+	var dependents []int64
+	for _, other := range r.notes {
+		if other.ID == id {
+			continue
+		}
+		for _, included := range core.Includes(other.Content, ids) {
+			if included == id {
+				dependents = append(dependents, other.ID)
+				break
+			}
+		}
+	}
+	sort.Slice(dependents, func(i, j int) bool { return dependents[i] < dependents[j] })
+
+	if policy == DependentsBlock && len(dependents) > 0 {
+		return dependents, ErrNoteHasDependents
+	}
+
+	now := time.Now()
+	if policy == DependentsMark {
+		for _, depID := range dependents {
+			dep := r.notes[depID]
+			r.update(dep, map[string]interface{}{"content": core.MarkIncludeDeleted(dep.Content, id, ids)}, now)
+		}
+	}
+
+	r.unindexHash(id, note.Content)
+	r.recordAudit(id, core.AuditDeleted, nil, now)
+	delete(r.notes, id)
+
+	return dependents, nil
+}
+
+// NormalizeAll пропускает каждую заметку через normalize и сохраняет те, что изменились.
+// Возвращает число измененных заметок.
+func (r *NoteRepoMem) NormalizeAll(normalize func(title, content string) (string, string)) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	changed := 0
+	now := time.Now()
+	for _, note := range r.notes {
+		title, content := normalize(note.Title, note.Content)
+		if title == note.Title && content == note.Content {
+			continue
+		}
+		r.update(note, map[string]interface{}{"title": title, "content": content}, now)
+		changed++
+	}
+	return changed
+}
+
+// ReplaceAll заменяет все содержимое хранилища переданными заметками с их ID и метками времени.
+// Журнал действий сохраняется: для каждой восстановленной заметки в него добавляется запись,
+// заметки, которых нет в копии, отмечаются как удаленные.
+func (r *NoteRepoMem) ReplaceAll(notes []core.Note) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	kept := make(map[int64]struct{}, len(notes))
+	for _, n := range notes {
+		kept[n.ID] = struct{}{}
+	}
+	for id := range r.notes {
+		if _, ok := kept[id]; !ok {
+			r.recordAudit(id, core.AuditDeleted, nil, now)
+		}
+	}
+
+	r.notes = make(map[int64]*core.Note, len(notes))
+	r.byHash = make(map[string]map[int64]struct{})
+	for _, n := range notes {
+		r.notes[n.ID] = &n
+		r.indexHash(n.ID, n.Content)
+		r.recordAudit(n.ID, core.AuditRestored, nil, now)
+		r.observeID(n.ID)
+	}
+}
+
+// AuditTrail возвращает журнал действий над заметкой в порядке их выполнения.
+// Журнал удаленной заметки сохраняется.
+func (r *NoteRepoMem) AuditTrail(id int64) ([]core.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries, exists := r.audit[id]
+	if !exists {
+		return nil, ErrNoteNotFound
+	}
+
+	trail := make([]core.AuditEntry, len(entries))
+	copy(trail, entries)
+	return trail, nil
+}
+
+// LeastRecentlyUpdated возвращает до limit заметок, которые дольше всех не менялись.
+// Для ни разу не редактированных заметок учитывается время создания.
+func (r *NoteRepoMem) LeastRecentlyUpdated(limit int) ([]core.Note, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notes := make([]core.Note, 0, len(r.notes))
+	for _, note := range r.notes {
+		notes = append(notes, *note)
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		ti, tj := notes[i].LastModified(), notes[j].LastModified()
+		if ti.Equal(tj) {
+			return notes[i].ID < notes[j].ID
+		}
+		return ti.Before(tj)
+	})
+
+	if limit < len(notes) {
+		notes = notes[:limit]
+	}
+
+	return notes, nil
+}
+
+// Untouched возвращает заметки, которые не редактировались после создания, от старых к новым
+func (r *NoteRepoMem) Untouched() ([]core.Note, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var notes []core.Note
+	for _, note := range r.notes {
+		if note.UpdatedAt == nil || note.UpdatedAt.Equal(note.CreatedAt) {
+			notes = append(notes, *note)
+		}
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		if notes[i].CreatedAt.Equal(notes[j].CreatedAt) {
+			return notes[i].ID < notes[j].ID
+		}
+		return notes[i].CreatedAt.Before(notes[j].CreatedAt)
+	})
+
+	return notes, nil
+}
+
+// FindByContentHash возвращает заметки, нормализованное содержимое которых дает указанный хеш
+func (r *NoteRepoMem) FindByContentHash(hash string) ([]core.Note, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := r.byHash[hash]
+	notes := make([]core.Note, 0, len(ids))
+	for id := range ids {
+		notes = append(notes, *r.notes[id])
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i].ID < notes[j].ID
+	})
+
+	return notes, nil
+}
+
+// RepairDanglingRefs находит ссылки [[id]] на несуществующие заметки.
+// При apply ссылки вырезаются из содержимого, а с mark заменяются на [[deleted:id]].
+// ids - формат ID в ссылках.
+func (r *NoteRepoMem) RepairDanglingRefs(apply, mark bool, ids core.RefIDs) ([]core.DanglingRefs, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []core.DanglingRefs
+	now := time.Now()
+	for _, note := range r.notes {
+		var missing []int64
+		for _, ref := range core.References(note.Content, ids) {
+			if _, exists := r.notes[ref]; !exists {
+				missing = append(missing, ref)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		result = append(result, core.DanglingRefs{NoteID: note.ID, Missing: missing})
+
+		if !apply {
+			continue
+		}
+
+		content := core.ReplaceReferences(note.Content, ids, func(id int64) (string, bool) {
+			if _, exists := r.notes[id]; exists {
+				return "", false
+			}
+			if mark {
+				return "[[deleted:" + core.FormatRefID(ids, id) + "]]", true
+			}
+			return "", true
+		})
+
+		r.update(note, map[string]interface{}{"content": content}, now)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].NoteID < result[j].NoteID
+	})
+
+	return result, nil
+}
+
+// GroupByPeriod группирует заметки по периоду создания (день, неделя, месяц).
+// Группы отсортированы от новых периодов к старым.
+func (r *NoteRepoMem) GroupByPeriod(granularity string, loc *time.Location) ([]core.NoteGroup, error) {
+	// проверяем заранее, чтобы ответ не зависел от того, есть ли заметки
+	if _, err := periodKey(time.Time{}, granularity); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	groups := make(map[string][]core.Note)
+	for _, note := range r.notes {
+		key, err := periodKey(note.CreatedAt.In(loc), granularity)
+		if err != nil {
+			return nil, err
+		}
+		groups[key] = append(groups[key], *note)
+	}
+
+	result := make([]core.NoteGroup, 0, len(groups))
+	for period, notes := range groups {
+		sort.Slice(notes, func(i, j int) bool {
+			return notes[i].CreatedAt.After(notes[j].CreatedAt)
+		})
+		result = append(result, core.NoteGroup{Period: period, Notes: notes})
+	}
+
+	// ключи периодов в формате ISO, поэтому строковое сравнение совпадает с хронологическим
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Period > result[j].Period
+	})
+
+	return result, nil
+}
+
+func periodKey(t time.Time, granularity string) (string, error) {
+	switch granularity {
+	case GranularityDay:
+		return t.Format("2006-01-02"), nil
+	case GranularityWeek:
+		// неделя начинается с понедельника
+		offset := (int(t.Weekday()) + 6) % 7
+		return t.AddDate(0, 0, -offset).Format("2006-01-02"), nil
+	case GranularityMonth:
+		return t.Format("2006-01"), nil
+	default:
+		return "", ErrInvalidGranularity
+	}
+}
+
+// Reindex заново строит производные индексы по текущему набору заметок.
+// Возвращает число заметок и число различных хешей содержимого.
+func (r *NoteRepoMem) Reindex() (notes, hashes int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.byHash = make(map[string]map[int64]struct{}, len(r.byHash))
+	for id, note := range r.notes {
+		r.indexHash(id, note.Content)
+	}
+
+	return len(r.notes), len(r.byHash)
+}
+
+// ContentHash - hex SHA-256 от нормализованного содержимого заметки:
+// переводы строк приводятся к \n, пробелы по краям отбрасываются
+func ContentHash(content string) string {
+	normalized := strings.TrimSpace(strings.ReplaceAll(content, "\r\n", "\n"))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *NoteRepoMem) observeID(id int64) {
+	if o, ok := r.ids.(idObserver); ok {
+		o.Observe(id)
+	}
+}
+
+func (r *NoteRepoMem) recordAudit(id int64, action string, fields []string, at time.Time) {
+	r.audit[id] = append(r.audit[id], core.AuditEntry{Action: action, Fields: fields, At: at})
+}
+
+func (r *NoteRepoMem) indexHash(id int64, content string) {
+	hash := ContentHash(content)
+	ids, ok := r.byHash[hash]
+	if !ok {
+		ids = make(map[int64]struct{})
+		r.byHash[hash] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func (r *NoteRepoMem) unindexHash(id int64, content string) {
+	hash := ContentHash(content)
+	delete(r.byHash[hash], id)
+	if len(r.byHash[hash]) == 0 {
+		delete(r.byHash, hash)
+	}
+}
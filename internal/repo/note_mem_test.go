@@ -0,0 +1,192 @@
+package repo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/notes-api/internal/core"
+)
+
+func mustTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	ts, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts
+}
+
+func TestGroupByPeriodDay(t *testing.T) {
+	r := NewNoteRepoMem()
+	for i, created := range []string{
+		"2024-03-01T00:00:00Z",
+		"2024-03-01T23:59:59Z",
+		"2024-03-02T00:00:00Z",
+		"2024-03-05T12:00:00Z",
+	} {
+		if _, err := r.Restore(core.Note{ID: int64(i + 1), Title: "n", CreatedAt: mustTime(t, created)}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	groups, err := r.GroupByPeriod(GranularityDay, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		period string
+		ids    []int64
+	}{
+		{"2024-03-05", []int64{4}},
+		{"2024-03-02", []int64{3}},
+		{"2024-03-01", []int64{2, 1}},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("got %d groups, want %d", len(groups), len(want))
+	}
+	for i, w := range want {
+		g := groups[i]
+		if g.Period != w.period || len(g.Notes) != len(w.ids) {
+			t.Fatalf("group %d = %s with %d notes, want %s with %d", i, g.Period, len(g.Notes), w.period, len(w.ids))
+		}
+		for j, id := range w.ids {
+			if g.Notes[j].ID != id {
+				t.Errorf("group %s note %d = %d, want %d", g.Period, j, g.Notes[j].ID, id)
+			}
+		}
+	}
+}
+
+func TestGroupByPeriodInvalidGranularity(t *testing.T) {
+	r := NewNoteRepoMem()
+	if _, err := r.GroupByPeriod("year", time.UTC); err != ErrInvalidGranularity {
+		t.Fatalf("empty store: err = %v, want ErrInvalidGranularity", err)
+	}
+}
+
+func TestReindexRepairsContentHashIndex(t *testing.T) {
+	r := NewNoteRepoMem()
+	a, _ := r.Create(core.Note{Title: "a", Content: "same"})
+	b, _ := r.Create(core.Note{Title: "b", Content: "same"})
+	c, _ := r.Create(core.Note{Title: "c", Content: "other"})
+
+	// портим индекс: теряем одну заметку и добавляем несуществующую
+	delete(r.byHash[ContentHash("same")], b)
+	r.byHash[ContentHash("stale")] = map[int64]struct{}{c: {}}
+
+	notes, hashes := r.Reindex()
+	if notes != 3 || hashes != 2 {
+		t.Fatalf("Reindex() = %d notes, %d hashes; want 3, 2", notes, hashes)
+	}
+
+	found, _ := r.FindByContentHash(ContentHash("same"))
+	if len(found) != 2 || found[0].ID != a || found[1].ID != b {
+		t.Fatalf("FindByContentHash(same) = %+v, want notes %d and %d", found, a, b)
+	}
+	if stale, _ := r.FindByContentHash(ContentHash("stale")); len(stale) != 0 {
+		t.Fatalf("stale hash still finds %+v", stale)
+	}
+}
+
+func TestAuditTrailRecordsActions(t *testing.T) {
+	r := NewNoteRepoMem()
+	id, _ := r.Create(core.Note{Title: "a", Content: "one"})
+
+	r.UpdatePartial(id, map[string]interface{}{"title": "b"})
+	r.UpdatePartial(id, map[string]interface{}{"title": "b", "content": "two"})
+	r.UpdatePartial(id, map[string]interface{}{"content": "two"})
+	if err := r.Delete(id); err != nil {
+		t.Fatal(err)
+	}
+
+	trail, err := r.AuditTrail(id)
+	if err != nil {
+		t.Fatalf("trail of a deleted note: %v", err)
+	}
+
+	want := []struct {
+		action string
+		fields string
+	}{
+		{core.AuditCreated, ""},
+		{core.AuditUpdated, "title"},
+		{core.AuditUpdated, "content"},
+		{core.AuditUpdated, ""},
+		{core.AuditDeleted, ""},
+	}
+	if len(trail) != len(want) {
+		t.Fatalf("trail has %d entries, want %d: %+v", len(trail), len(want), trail)
+	}
+	for i, w := range want {
+		e := trail[i]
+		if e.Action != w.action || strings.Join(e.Fields, ",") != w.fields {
+			t.Errorf("entry %d = %s %v, want %s [%s]", i, e.Action, e.Fields, w.action, w.fields)
+		}
+		if i > 0 && e.At.Before(trail[i-1].At) {
+			t.Errorf("entry %d is older than the previous one", i)
+		}
+	}
+
+	if _, err := r.AuditTrail(id + 1); err != ErrNoteNotFound {
+		t.Fatalf("trail of an unknown note: err = %v, want ErrNoteNotFound", err)
+	}
+}
+
+func TestReplaceAllAuditsDroppedNotes(t *testing.T) {
+	r := NewNoteRepoMem()
+	kept, _ := r.Create(core.Note{Title: "kept"})
+	dropped, _ := r.Create(core.Note{Title: "dropped"})
+
+	r.ReplaceAll([]core.Note{{ID: kept, Title: "kept", CreatedAt: time.Now()}})
+
+	if _, err := r.GetByID(dropped); err != ErrNoteNotFound {
+		t.Fatalf("dropped note still present: err = %v", err)
+	}
+	for id, want := range map[int64]string{kept: core.AuditRestored, dropped: core.AuditDeleted} {
+		trail, err := r.AuditTrail(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last := trail[len(trail)-1].Action; last != want {
+			t.Errorf("note %d: last audit action = %s, want %s", id, last, want)
+		}
+	}
+}
+
+func TestCursorReadsNotesLazily(t *testing.T) {
+	r := NewNoteRepoMem()
+	for _, title := range []string{"a", "b", "c"} {
+		r.Create(core.Note{Title: title})
+	}
+
+	c := r.Cursor()
+	first, ok := c.Next()
+	if !ok || first.ID != 1 {
+		t.Fatalf("first = %+v, %v; want note 1", first, ok)
+	}
+
+	// изменения после создания курсора видны, удаленные заметки пропускаются,
+	// а созданные позже в обход не попадают
+	r.UpdatePartial(3, map[string]interface{}{"title": "c2"})
+	r.Delete(2)
+	r.Create(core.Note{Title: "d"})
+
+	var titles []string
+	for n, ok := c.Next(); ok; n, ok = c.Next() {
+		titles = append(titles, n.Title)
+	}
+	if strings.Join(titles, ",") != "c2" {
+		t.Fatalf("rest of the walk = %v, want [c2]", titles)
+	}
+
+	var seen []int64
+	r.Each(func(n core.Note) bool {
+		seen = append(seen, n.ID)
+		return len(seen) < 2
+	})
+	if len(seen) != 2 || seen[0] != 1 || seen[1] != 3 {
+		t.Fatalf("Each visited %v, want to stop after [1 3]", seen)
+	}
+}
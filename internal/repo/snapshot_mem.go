@@ -0,0 +1,77 @@
+package repo
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+
+	"example.com/notes-api/internal/core"
+)
+
+var (
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+)
+
+// SnapshotRepoMem хранит неизменяемые снимки заметок, доступные по токену
+type SnapshotRepoMem struct {
+	mu        sync.RWMutex
+	snapshots map[string]*core.Snapshot
+}
+
+func NewSnapshotRepoMem() *SnapshotRepoMem {
+	return &SnapshotRepoMem{
+		snapshots: make(map[string]*core.Snapshot),
+	}
+}
+
+// Create сохраняет копию заметки; ttl <= 0 - снимок без срока действия
+func (r *SnapshotRepoMem) Create(n core.Note, ttl time.Duration) (*core.Snapshot, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s := core.Snapshot{Token: token, Note: n, CreatedAt: now}
+	if ttl > 0 {
+		expires := now.Add(ttl)
+		s.ExpiresAt = &expires
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.snapshots[token] = &s
+
+	snapshotCopy := s
+	return &snapshotCopy, nil
+}
+
+// GetByToken возвращает снимок; просроченные снимки удаляются и считаются отсутствующими
+func (r *SnapshotRepoMem) GetByToken(token string) (*core.Snapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, exists := r.snapshots[token]
+	if !exists {
+		return nil, ErrSnapshotNotFound
+	}
+
+	if s.ExpiresAt != nil && time.Now().After(*s.ExpiresAt) {
+		delete(r.snapshots, token)
+		return nil, ErrSnapshotNotFound
+	}
+
+	snapshotCopy := *s
+	return &snapshotCopy, nil
+}
+
+func newToken() (string, error) {
+	var b [18]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/notes-api/internal/core"
+)
+
+var (
+	ErrViewNotFound = errors.New("view not found")
+	ErrViewExists   = errors.New("view with this name already exists")
+)
+
+// ViewRepoMem хранит именованные фильтры заметок
+type ViewRepoMem struct {
+	mu    sync.RWMutex
+	views map[string]*core.View
+}
+
+func NewViewRepoMem() *ViewRepoMem {
+	return &ViewRepoMem{
+		views: make(map[string]*core.View),
+	}
+}
+
+// Create сохраняет фильтр под именем; занятое имя - ErrViewExists
+func (r *ViewRepoMem) Create(name string, filter map[string]string) (*core.View, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.views[name]; exists {
+		return nil, ErrViewExists
+	}
+
+	v := core.View{Name: name, Filter: copyFilter(filter), CreatedAt: time.Now()}
+	r.views[name] = &v
+
+	viewCopy := v
+	viewCopy.Filter = copyFilter(v.Filter)
+	return &viewCopy, nil
+}
+
+func (r *ViewRepoMem) GetByName(name string) (*core.View, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	v, exists := r.views[name]
+	if !exists {
+		return nil, ErrViewNotFound
+	}
+
+	viewCopy := *v
+	viewCopy.Filter = copyFilter(v.Filter)
+	return &viewCopy, nil
+}
+
+// GetAll возвращает представления, отсортированные по имени
+func (r *ViewRepoMem) GetAll() []core.View {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	views := make([]core.View, 0, len(r.views))
+	for _, v := range r.views {
+		viewCopy := *v
+		viewCopy.Filter = copyFilter(v.Filter)
+		views = append(views, viewCopy)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views
+}
+
+func (r *ViewRepoMem) Delete(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.views[name]; !exists {
+		return ErrViewNotFound
+	}
+	delete(r.views, name)
+	return nil
+}
+
+func copyFilter(filter map[string]string) map[string]string {
+	out := make(map[string]string, len(filter))
+	for k, v := range filter {
+		out[k] = v
+	}
+	return out
+}